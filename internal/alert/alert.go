@@ -0,0 +1,198 @@
+// Package alert evaluates threshold rules against the sensor readings and sends notifications to webhooks.
+package alert
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+// Config contains the alerting rules and the receivers of the notifications.
+type Config struct {
+	Rules     []Rule     `yaml:"rules"`
+	Receivers []Receiver `yaml:"receivers"`
+}
+
+// Validate checks the rules and receivers for errors.
+func (c Config) Validate() error {
+	if _, err := compileRules(c.Rules); err != nil {
+		return err
+	}
+
+	if _, err := compileReceivers(c.Receivers); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Rule describes a condition of a measurement, which causes an alert.
+// The alert fires when the value is above or below the threshold for at least the duration in For.
+// It is resolved once the value is back on the other side of the threshold by at least Hysteresis.
+type Rule struct {
+	Name        string               `yaml:"name"`
+	Module      collector.ModuleRule `yaml:"module"`
+	Measurement string               `yaml:"measurement"`
+	Above       *float64             `yaml:"above"`
+	Below       *float64             `yaml:"below"`
+	Hysteresis  float64              `yaml:"hysteresis"`
+	For         time.Duration        `yaml:"for"`
+}
+
+// active returns true, if the value violates the threshold.
+func (r Rule) active(value float64) bool {
+	if r.Above != nil {
+		return value > *r.Above
+	}
+
+	return value < *r.Below
+}
+
+// resolved returns true, if the value is back within the threshold including the hysteresis.
+func (r Rule) resolved(value float64) bool {
+	if r.Above != nil {
+		return value <= *r.Above-r.Hysteresis
+	}
+
+	return value >= *r.Below+r.Hysteresis
+}
+
+func (r Rule) threshold() float64 {
+	if r.Above != nil {
+		return *r.Above
+	}
+
+	return *r.Below
+}
+
+type compiledRule struct {
+	Rule
+	filter *collector.ModuleFilter
+}
+
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	names := make(map[string]bool, len(rules))
+	result := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		switch {
+		case rule.Name == "":
+			return nil, fmt.Errorf("rule %d: needs a name", i+1)
+		case names[rule.Name]:
+			return nil, fmt.Errorf("rule %d: duplicate name %q", i+1, rule.Name)
+		case !collector.KnownMeasurement(rule.Measurement):
+			return nil, fmt.Errorf("rule %q: unknown measurement %q", rule.Name, rule.Measurement)
+		case (rule.Above == nil) == (rule.Below == nil):
+			return nil, fmt.Errorf("rule %q: needs either above or below", rule.Name)
+		case rule.Hysteresis < 0:
+			return nil, fmt.Errorf("rule %q: hysteresis can not be negative", rule.Name)
+		case rule.For < 0:
+			return nil, fmt.Errorf("rule %q: duration can not be negative", rule.Name)
+		}
+		names[rule.Name] = true
+
+		var filter *collector.ModuleFilter
+		if rule.Module != (collector.ModuleRule{}) {
+			var err error
+			filter, err = collector.NewModuleFilter([]collector.ModuleRule{rule.Module}, nil)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+		}
+
+		result = append(result, compiledRule{
+			Rule:   rule,
+			filter: filter,
+		})
+	}
+
+	return result, nil
+}
+
+// Format selects the payload sent to a receiver.
+type Format string
+
+const (
+	// FormatJSON sends the notification as JSON object.
+	FormatJSON Format = "json"
+	// FormatSlack sends a message for a Slack incoming webhook.
+	FormatSlack Format = "slack"
+	// FormatDiscord sends a message for a Discord webhook.
+	FormatDiscord Format = "discord"
+	// FormatNtfy sends a message to a ntfy topic.
+	FormatNtfy Format = "ntfy"
+)
+
+// Valid returns true if the format is known.
+func (f Format) Valid() bool {
+	switch f {
+	case FormatJSON, FormatSlack, FormatDiscord, FormatNtfy:
+		return true
+	default:
+		return false
+	}
+}
+
+// defaultTemplate is used for the message text, if the receiver does not have its own template.
+const defaultTemplate = `{{if eq .Status "firing"}}Alert{{else}}Resolved{{end}} {{.Rule}}: {{.Measurement}} of {{.Module}} ({{.Station}}) is {{.Value}}`
+
+// Receiver is a webhook, which receives the notifications.
+// The message text of the Slack, Discord and ntfy formats can be changed using a Go template.
+type Receiver struct {
+	Name     string `yaml:"name"`
+	URL      string `yaml:"url"`
+	Format   Format `yaml:"format"`
+	Template string `yaml:"template"`
+}
+
+type compiledReceiver struct {
+	Receiver
+	template *template.Template
+}
+
+func compileReceivers(receivers []Receiver) ([]compiledReceiver, error) {
+	names := make(map[string]bool, len(receivers))
+	result := make([]compiledReceiver, 0, len(receivers))
+	for i, receiver := range receivers {
+		switch {
+		case receiver.Name == "":
+			return nil, fmt.Errorf("receiver %d: needs a name", i+1)
+		case names[receiver.Name]:
+			return nil, fmt.Errorf("receiver %d: duplicate name %q", i+1, receiver.Name)
+		case receiver.URL == "":
+			return nil, fmt.Errorf("receiver %q: needs a URL", receiver.Name)
+		}
+		names[receiver.Name] = true
+
+		if _, err := url.ParseRequestURI(receiver.URL); err != nil {
+			return nil, fmt.Errorf("receiver %q: invalid URL: %w", receiver.Name, err)
+		}
+
+		if receiver.Format == "" {
+			receiver.Format = FormatJSON
+		}
+
+		if !receiver.Format.Valid() {
+			return nil, fmt.Errorf("receiver %q: unknown format %q", receiver.Name, receiver.Format)
+		}
+
+		text := receiver.Template
+		if text == "" {
+			text = defaultTemplate
+		}
+
+		tmpl, err := template.New(receiver.Name).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("receiver %q: invalid template: %w", receiver.Name, err)
+		}
+
+		result = append(result, compiledReceiver{
+			Receiver: receiver,
+			template: tmpl,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,202 @@
+package alert
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+const (
+	// StatusFiring is the status of notifications about new alerts.
+	StatusFiring = "firing"
+	// StatusResolved is the status of notifications about alerts which are no longer active.
+	StatusResolved = "resolved"
+
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var firingDesc = prometheus.NewDesc(
+	"netatmo_exporter_alerts_firing",
+	"Number of firing alerts by rule.",
+	[]string{"rule"}, nil)
+
+// stateKey identifies the alert of a rule for a single module.
+type stateKey struct {
+	rule   string
+	module string
+}
+
+type state struct {
+	pendingSince time.Time
+	firing       bool
+	reading      collector.Reading
+}
+
+// Manager evaluates the alerting rules and sends the notifications.
+type Manager struct {
+	log           logrus.FieldLogger
+	client        *http.Client
+	rules         []compiledRule
+	receivers     []compiledReceiver
+	notifications *prometheus.CounterVec
+
+	lock    sync.Mutex
+	states  map[stateKey]state
+	queue   []Notification
+	sending bool
+	wg      sync.WaitGroup
+}
+
+// New creates a Manager from the configuration. The HTTP client is used for sending the notifications.
+func New(log logrus.FieldLogger, cfg Config, client *http.Client) (*Manager, error) {
+	rules, err := compileRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	receivers, err := compileReceivers(cfg.Receivers)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "netatmo_exporter_alert_notifications_total",
+		Help: "Number of notifications sent by receiver and result.",
+	}, []string{"receiver", "result"})
+	for _, r := range receivers {
+		notifications.WithLabelValues(r.Name, resultSuccess)
+		notifications.WithLabelValues(r.Name, resultError)
+	}
+
+	return &Manager{
+		log:           log,
+		client:        client,
+		rules:         rules,
+		receivers:     receivers,
+		notifications: notifications,
+		states:        make(map[stateKey]state),
+	}, nil
+}
+
+// Evaluate checks the rules against the readings and sends notifications for alerts which start firing or are resolved.
+// The notifications are sent in the background one after the other, in the order they were created.
+func (m *Manager) Evaluate(now time.Time, readings []collector.Reading) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.queue = append(m.queue, m.evaluate(now, readings)...)
+	if len(m.queue) == 0 || m.sending {
+		return
+	}
+
+	m.sending = true
+	m.wg.Add(1)
+	go m.sendQueue()
+}
+
+// sendQueue sends the queued notifications until the queue is empty.
+func (m *Manager) sendQueue() {
+	defer m.wg.Done()
+
+	for {
+		m.lock.Lock()
+		if len(m.queue) == 0 {
+			m.sending = false
+			m.lock.Unlock()
+			return
+		}
+		n := m.queue[0]
+		m.queue = m.queue[1:]
+		m.lock.Unlock()
+
+		m.notify(n)
+	}
+}
+
+// evaluate updates the state of the alerts and returns the notifications which need to be sent.
+// Alerts of modules which are missing from the readings are resolved. The caller needs to hold the lock.
+func (m *Manager) evaluate(now time.Time, readings []collector.Reading) []Notification {
+	var notifications []Notification
+	seen := make(map[stateKey]bool, len(m.states))
+	for _, rule := range m.rules {
+		for _, reading := range readings {
+			if reading.Measurement != rule.Measurement || !rule.filter.Match(reading.Module, reading.StationName, reading.HomeName) {
+				continue
+			}
+
+			key := stateKey{
+				rule:   rule.Name,
+				module: reading.Module.ID,
+			}
+			seen[key] = true
+			current := m.states[key]
+			current.reading = reading
+			switch {
+			case current.firing:
+				if rule.resolved(reading.Value) {
+					notifications = append(notifications, newNotification(StatusResolved, rule.Rule, reading, now))
+					delete(m.states, key)
+				}
+			case rule.active(reading.Value):
+				if current.pendingSince.IsZero() {
+					current.pendingSince = now
+				}
+
+				if now.Sub(current.pendingSince) >= rule.For {
+					current.firing = true
+					notifications = append(notifications, newNotification(StatusFiring, rule.Rule, reading, now))
+				}
+				m.states[key] = current
+			default:
+				delete(m.states, key)
+			}
+		}
+
+		for key, current := range m.states {
+			if key.rule != rule.Name || seen[key] {
+				continue
+			}
+
+			if current.firing {
+				notifications = append(notifications, newNotification(StatusResolved, rule.Rule, current.reading, now))
+			}
+			delete(m.states, key)
+		}
+	}
+
+	return notifications
+}
+
+// Wait blocks until all notifications have been sent.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Describe implements prometheus.Collector
+func (m *Manager) Describe(ch chan<- *prometheus.Desc) {
+	ch <- firingDesc
+	m.notifications.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *Manager) Collect(ch chan<- prometheus.Metric) {
+	m.lock.Lock()
+	firing := make(map[string]int, len(m.rules))
+	for key, s := range m.states {
+		if s.firing {
+			firing[key.rule]++
+		}
+	}
+	m.lock.Unlock()
+
+	for _, rule := range m.rules {
+		ch <- prometheus.MustNewConstMetric(firingDesc, prometheus.GaugeValue, float64(firing[rule.Name]), rule.Name)
+	}
+	m.notifications.Collect(ch)
+}
@@ -0,0 +1,379 @@
+package alert
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+var testModule = &api.Device{
+	Device: netatmo.Device{
+		ID:         "aa:bb:cc:dd:ee:f1",
+		ModuleName: "Outside",
+		Type:       "NAModule1",
+	},
+}
+
+func testReading(measurement string, value float64) collector.Reading {
+	return collector.Reading{
+		Module:      testModule,
+		ModuleName:  "Outside",
+		StationName: "Home (Living Room)",
+		HomeName:    "Home",
+		Measurement: measurement,
+		Value:       value,
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tt := []struct {
+		desc    string
+		config  Config
+		wantErr string
+	}{
+		{
+			desc: "empty",
+		},
+		{
+			desc: "valid",
+			config: Config{
+				Rules: []Rule{
+					{Name: "frost", Measurement: "temperature", Below: float64Ptr(0), Module: collector.ModuleRule{Type: "NAModule1"}},
+				},
+				Receivers: []Receiver{
+					{Name: "chat", URL: "https://hooks.example.com/test", Format: FormatSlack},
+				},
+			},
+		},
+		{
+			desc: "rule without name",
+			config: Config{
+				Rules: []Rule{
+					{Measurement: "temperature", Below: float64Ptr(0)},
+				},
+			},
+			wantErr: "rule 1: needs a name",
+		},
+		{
+			desc: "duplicate rule",
+			config: Config{
+				Rules: []Rule{
+					{Name: "frost", Measurement: "temperature", Below: float64Ptr(0)},
+					{Name: "frost", Measurement: "temperature", Below: float64Ptr(2)},
+				},
+			},
+			wantErr: "rule 2: duplicate name \"frost\"",
+		},
+		{
+			desc: "unknown measurement",
+			config: Config{
+				Rules: []Rule{
+					{Name: "bright", Measurement: "brightness", Above: float64Ptr(100)},
+				},
+			},
+			wantErr: "rule \"bright\": unknown measurement \"brightness\"",
+		},
+		{
+			desc: "above and below",
+			config: Config{
+				Rules: []Rule{
+					{Name: "range", Measurement: "humidity", Above: float64Ptr(70), Below: float64Ptr(30)},
+				},
+			},
+			wantErr: "rule \"range\": needs either above or below",
+		},
+		{
+			desc: "negative hysteresis",
+			config: Config{
+				Rules: []Rule{
+					{Name: "co2", Measurement: "co2", Above: float64Ptr(1500), Hysteresis: -100},
+				},
+			},
+			wantErr: "rule \"co2\": hysteresis can not be negative",
+		},
+		{
+			desc: "receiver without URL",
+			config: Config{
+				Receivers: []Receiver{
+					{Name: "chat"},
+				},
+			},
+			wantErr: "receiver \"chat\": needs a URL",
+		},
+		{
+			desc: "unknown format",
+			config: Config{
+				Receivers: []Receiver{
+					{Name: "chat", URL: "https://hooks.example.com/test", Format: "irc"},
+				},
+			},
+			wantErr: "receiver \"chat\": unknown format \"irc\"",
+		},
+		{
+			desc: "invalid template",
+			config: Config{
+				Receivers: []Receiver{
+					{Name: "chat", URL: "https://hooks.example.com/test", Template: "{{.Rule"},
+				},
+			},
+			wantErr: "receiver \"chat\": invalid template: template: chat:1: unclosed action",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.config.Validate()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %q, want none", err)
+			}
+		})
+	}
+}
+
+func TestManagerEvaluate(t *testing.T) {
+	tt := []struct {
+		desc       string
+		rule       Rule
+		readings   []collector.Reading
+		wantStatus []string
+	}{
+		{
+			desc: "fires immediately",
+			rule: Rule{Name: "co2", Measurement: "co2", Above: float64Ptr(1500)},
+			readings: []collector.Reading{
+				testReading("co2", 800),
+				testReading("co2", 1600),
+				testReading("co2", 1700),
+			},
+			wantStatus: []string{"", StatusFiring, ""},
+		},
+		{
+			desc: "other measurement",
+			rule: Rule{Name: "co2", Measurement: "co2", Above: float64Ptr(1500)},
+			readings: []collector.Reading{
+				testReading("noise", 1600),
+			},
+			wantStatus: []string{""},
+		},
+		{
+			desc: "module does not match",
+			rule: Rule{Name: "co2", Measurement: "co2", Above: float64Ptr(1500), Module: collector.ModuleRule{Type: "NAMain"}},
+			readings: []collector.Reading{
+				testReading("co2", 1600),
+			},
+			wantStatus: []string{""},
+		},
+		{
+			desc: "resolves",
+			rule: Rule{Name: "frost", Measurement: "temperature", Below: float64Ptr(0)},
+			readings: []collector.Reading{
+				testReading("temperature", -2),
+				testReading("temperature", 1),
+				testReading("temperature", -1),
+			},
+			wantStatus: []string{StatusFiring, StatusResolved, StatusFiring},
+		},
+		{
+			desc: "hysteresis",
+			rule: Rule{Name: "frost", Measurement: "temperature", Below: float64Ptr(0), Hysteresis: 2},
+			readings: []collector.Reading{
+				testReading("temperature", -2),
+				testReading("temperature", 1),
+				testReading("temperature", -1),
+				testReading("temperature", 2),
+			},
+			wantStatus: []string{StatusFiring, "", "", StatusResolved},
+		},
+		{
+			desc: "for duration",
+			rule: Rule{Name: "humid", Measurement: "humidity", Above: float64Ptr(70), For: 20 * time.Minute},
+			readings: []collector.Reading{
+				testReading("humidity", 75),
+				testReading("humidity", 80),
+				testReading("humidity", 80),
+				testReading("humidity", 60),
+			},
+			wantStatus: []string{"", "", StatusFiring, StatusResolved},
+		},
+		{
+			desc: "for duration interrupted",
+			rule: Rule{Name: "humid", Measurement: "humidity", Above: float64Ptr(70), For: 20 * time.Minute},
+			readings: []collector.Reading{
+				testReading("humidity", 75),
+				testReading("humidity", 65),
+				testReading("humidity", 80),
+				testReading("humidity", 80),
+			},
+			wantStatus: []string{"", "", "", ""},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := New(logrus.New(), Config{Rules: []Rule{tc.rule}}, http.DefaultClient)
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			for i, reading := range tc.readings {
+				now := time.Unix(3600, 0).Add(time.Duration(i) * 10 * time.Minute)
+				status := ""
+				for _, n := range m.evaluate(now, []collector.Reading{reading}) {
+					status = n.Status
+				}
+
+				if status != tc.wantStatus[i] {
+					t.Errorf("reading %d: got status %q, want %q", i, status, tc.wantStatus[i])
+				}
+			}
+		})
+	}
+}
+
+func TestManagerEvaluateMissingModule(t *testing.T) {
+	m, err := New(logrus.New(), Config{
+		Rules: []Rule{
+			{Name: "frost", Measurement: "temperature", Below: float64Ptr(0)},
+			{Name: "humid", Measurement: "humidity", Above: float64Ptr(70), For: 20 * time.Minute},
+		},
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	now := time.Unix(3600, 0)
+	m.evaluate(now, []collector.Reading{
+		testReading("temperature", -2),
+		testReading("humidity", 80),
+	})
+
+	notifications := m.evaluate(now.Add(10*time.Minute), nil)
+	if len(notifications) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(notifications))
+	}
+	if n := notifications[0]; n.Status != StatusResolved || n.Rule != "frost" || n.Value != -2 {
+		t.Errorf("got notification %+v, want frost resolved with last value", n)
+	}
+
+	if len(m.states) != 0 {
+		t.Errorf("got %d alert states, want none", len(m.states))
+	}
+}
+
+func TestManagerNotificationOrder(t *testing.T) {
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"status":"firing"`) {
+			// Slow down the first notification, so that a later one would overtake it.
+			time.Sleep(50 * time.Millisecond)
+		}
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	m, err := New(logrus.New(), Config{
+		Rules: []Rule{
+			{Name: "frost", Measurement: "temperature", Below: float64Ptr(0)},
+		},
+		Receivers: []Receiver{
+			{Name: "hook", URL: server.URL},
+		},
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	m.Evaluate(time.Unix(3600, 0), []collector.Reading{testReading("temperature", -2)})
+	m.Evaluate(time.Unix(4200, 0), []collector.Reading{testReading("temperature", 1)})
+	m.Wait()
+	close(received)
+
+	var statuses []string
+	for body := range received {
+		switch {
+		case strings.Contains(body, `"status":"firing"`):
+			statuses = append(statuses, StatusFiring)
+		case strings.Contains(body, `"status":"resolved"`):
+			statuses = append(statuses, StatusResolved)
+		}
+	}
+
+	wantStatuses := []string{StatusFiring, StatusResolved}
+	if strings.Join(statuses, ",") != strings.Join(wantStatuses, ",") {
+		t.Errorf("got statuses %q, want %q", statuses, wantStatuses)
+	}
+}
+
+func TestManagerMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	cfg := Config{
+		Rules: []Rule{
+			{Name: "co2", Measurement: "co2", Above: float64Ptr(1500)},
+			{Name: "frost", Measurement: "temperature", Below: float64Ptr(0)},
+		},
+		Receivers: []Receiver{
+			{Name: "good", URL: server.URL + "/ok"},
+			{Name: "bad", URL: server.URL + "/fail"},
+		},
+	}
+	m, err := New(logrus.New(), cfg, server.Client())
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	m.Evaluate(time.Unix(3600, 0), []collector.Reading{
+		testReading("co2", 1600),
+		testReading("temperature", 5),
+	})
+	m.Wait()
+
+	wantMetrics := `# HELP netatmo_exporter_alert_notifications_total Number of notifications sent by receiver and result.
+# TYPE netatmo_exporter_alert_notifications_total counter
+netatmo_exporter_alert_notifications_total{receiver="bad",result="error"} 1
+netatmo_exporter_alert_notifications_total{receiver="bad",result="success"} 0
+netatmo_exporter_alert_notifications_total{receiver="good",result="error"} 0
+netatmo_exporter_alert_notifications_total{receiver="good",result="success"} 1
+# HELP netatmo_exporter_alerts_firing Number of firing alerts by rule.
+# TYPE netatmo_exporter_alerts_firing gauge
+netatmo_exporter_alerts_firing{rule="co2"} 1
+netatmo_exporter_alerts_firing{rule="frost"} 0
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(wantMetrics)); err != nil {
+		t.Error(err)
+	}
+}
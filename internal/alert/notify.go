@@ -0,0 +1,113 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+// Notification is sent to the receivers when an alert starts firing or is resolved.
+type Notification struct {
+	Status      string    `json:"status"`
+	Rule        string    `json:"rule"`
+	Module      string    `json:"module"`
+	ModuleID    string    `json:"moduleId"`
+	Station     string    `json:"station"`
+	Home        string    `json:"home"`
+	Measurement string    `json:"measurement"`
+	Value       float64   `json:"value"`
+	Threshold   float64   `json:"threshold"`
+	Time        time.Time `json:"time"`
+}
+
+func newNotification(status string, rule Rule, reading collector.Reading, now time.Time) Notification {
+	return Notification{
+		Status:      status,
+		Rule:        rule.Name,
+		Module:      reading.ModuleName,
+		ModuleID:    reading.Module.ID,
+		Station:     reading.StationName,
+		Home:        reading.HomeName,
+		Measurement: reading.Measurement,
+		Value:       reading.Value,
+		Threshold:   rule.threshold(),
+		Time:        now,
+	}
+}
+
+// notify sends the notification to all receivers.
+func (m *Manager) notify(n Notification) {
+	m.log.Infof("Alert %s %s: %s of %s is %g", n.Rule, n.Status, n.Measurement, n.Module, n.Value)
+
+	for _, r := range m.receivers {
+		result := resultSuccess
+		if err := m.send(r, n); err != nil {
+			m.log.Errorf("Error sending notification to %s: %s", r.Name, err)
+			result = resultError
+		}
+		m.notifications.WithLabelValues(r.Name, result).Inc()
+	}
+}
+
+func (m *Manager) send(r compiledReceiver, n Notification) error {
+	req, err := newRequest(r, n)
+	if err != nil {
+		return err
+	}
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	return nil
+}
+
+// newRequest creates the request for the receiver using the payload of its format.
+func newRequest(r compiledReceiver, n Notification) (*http.Request, error) {
+	var text strings.Builder
+	if err := r.template.Execute(&text, n); err != nil {
+		return nil, fmt.Errorf("error rendering template: %w", err)
+	}
+
+	var body []byte
+	contentType := "application/json"
+	switch r.Format {
+	case FormatSlack:
+		body, _ = json.Marshal(map[string]string{"text": text.String()})
+	case FormatDiscord:
+		body, _ = json.Marshal(map[string]string{"content": text.String()})
+	case FormatNtfy:
+		body = []byte(text.String())
+		contentType = "text/plain"
+	default:
+		body, _ = json.Marshal(n)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	if r.Format == FormatNtfy {
+		req.Header.Set("Title", fmt.Sprintf("%s %s", n.Rule, n.Status))
+		if n.Status == StatusFiring {
+			req.Header.Set("Tags", "warning")
+		} else {
+			req.Header.Set("Tags", "white_check_mark")
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+type request struct {
+	contentType string
+	title       string
+	tags        string
+	body        string
+}
+
+func TestManagerNotify(t *testing.T) {
+	tt := []struct {
+		desc        string
+		receiver    Receiver
+		wantRequest request
+	}{
+		{
+			desc: "json",
+			receiver: Receiver{
+				Name: "json",
+			},
+			wantRequest: request{
+				contentType: "application/json",
+				body:        `{"status":"firing","rule":"frost","module":"Outside","moduleId":"aa:bb:cc:dd:ee:f1","station":"Home (Living Room)","home":"Home","measurement":"temperature","value":-2.5,"threshold":0,"time":"1970-01-01T01:00:00Z"}`,
+			},
+		},
+		{
+			desc: "slack",
+			receiver: Receiver{
+				Name:   "slack",
+				Format: FormatSlack,
+			},
+			wantRequest: request{
+				contentType: "application/json",
+				body:        `{"text":"Alert frost: temperature of Outside (Home (Living Room)) is -2.5"}`,
+			},
+		},
+		{
+			desc: "discord with template",
+			receiver: Receiver{
+				Name:     "discord",
+				Format:   FormatDiscord,
+				Template: "{{.Module}} is freezing: {{.Value}} below {{.Threshold}}",
+			},
+			wantRequest: request{
+				contentType: "application/json",
+				body:        `{"content":"Outside is freezing: -2.5 below 0"}`,
+			},
+		},
+		{
+			desc: "ntfy",
+			receiver: Receiver{
+				Name:   "ntfy",
+				Format: FormatNtfy,
+			},
+			wantRequest: request{
+				contentType: "text/plain",
+				title:       "frost firing",
+				tags:        "warning",
+				body:        "Alert frost: temperature of Outside (Home (Living Room)) is -2.5",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			received := make(chan request, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received <- request{
+					contentType: r.Header.Get("Content-Type"),
+					title:       r.Header.Get("Title"),
+					tags:        r.Header.Get("Tags"),
+					body:        string(body),
+				}
+			}))
+			defer server.Close()
+
+			receiver := tc.receiver
+			receiver.URL = server.URL
+			cfg := Config{
+				Rules: []Rule{
+					{Name: "frost", Measurement: "temperature", Below: float64Ptr(0)},
+				},
+				Receivers: []Receiver{receiver},
+			}
+			m, err := New(logrus.New(), cfg, server.Client())
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			m.Evaluate(time.Unix(3600, 0).UTC(), []collector.Reading{
+				testReading("temperature", -2.5),
+			})
+
+			select {
+			case got := <-received:
+				if got != tc.wantRequest {
+					t.Errorf("got request %+v, want %+v", got, tc.wantRequest)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for notification")
+			}
+			m.Wait()
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrBudgetExhausted is returned when a request is refused, because it would exceed the configured rate limits.
+var ErrBudgetExhausted = errors.New("API request budget exhausted")
+
+// DefaultLimits mirrors the per-user rate limits documented by NetAtmo.
+var DefaultLimits = Limits{
+	{Requests: 50, Window: 10 * time.Second},
+	{Requests: 500, Window: time.Hour},
+}
+
+// Limit allows a number of requests in a sliding time window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// String returns the limit in the format "requests/window", for example "50/10s".
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, formatWindow(l.Window))
+}
+
+// ParseLimit parses a limit in the format "requests/window".
+func ParseLimit(value string) (Limit, error) {
+	requestsStr, windowStr, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q needs to have the format requests/window", value)
+	}
+
+	requests, err := strconv.Atoi(requestsStr)
+	if err != nil {
+		return Limit{}, fmt.Errorf("can not parse requests of limit %q: %w", value, err)
+	}
+
+	window, err := time.ParseDuration(windowStr)
+	if err != nil {
+		return Limit{}, fmt.Errorf("can not parse window of limit %q: %w", value, err)
+	}
+
+	if requests <= 0 || window <= 0 {
+		return Limit{}, fmt.Errorf("limit %q needs a positive number of requests and window", value)
+	}
+
+	return Limit{
+		Requests: requests,
+		Window:   window,
+	}, nil
+}
+
+// Limits is a list of rate limits, which all need to be satisfied. It implements pflag.Value.
+type Limits []Limit
+
+func (l *Limits) Type() string {
+	return "limits"
+}
+
+func (l *Limits) String() string {
+	parts := make([]string, 0, len(*l))
+	for _, limit := range *l {
+		parts = append(parts, limit.String())
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Set parses a comma-separated list of limits. An empty value or "none" disables all limits.
+func (l *Limits) Set(value string) error {
+	if value = strings.TrimSpace(value); value == "" || value == "none" {
+		*l = Limits{}
+		return nil
+	}
+
+	var result Limits
+	for _, part := range strings.Split(value, ",") {
+		limit, err := ParseLimit(part)
+		if err != nil {
+			return err
+		}
+
+		result = append(result, limit)
+	}
+	*l = result
+
+	return nil
+}
+
+// Budget keeps track of the requests made to the NetAtmo API and how many more requests the limits allow.
+// A nil Budget allows all requests.
+type Budget struct {
+	limits Limits
+	clock  func() time.Time
+
+	lock     sync.Mutex
+	requests []time.Time
+
+	remainingDesc *prometheus.Desc
+	limitDesc     *prometheus.Desc
+	refused       prometheus.Counter
+}
+
+var _ prometheus.Collector = &Budget{}
+
+// NewBudget creates a new Budget enforcing the provided limits.
+func NewBudget(limits Limits) *Budget {
+	return &Budget{
+		limits: limits,
+		clock:  time.Now,
+		remainingDesc: prometheus.NewDesc(
+			metricsPrefix+"budget_remaining",
+			"Number of requests to the NetAtmo API left in the sliding window.",
+			[]string{"window"},
+			nil),
+		limitDesc: prometheus.NewDesc(
+			metricsPrefix+"budget_limit",
+			"Number of requests to the NetAtmo API allowed in the sliding window.",
+			[]string{"window"},
+			nil),
+		refused: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: metricsPrefix + "budget_refused_total",
+			Help: "Number of requests to the NetAtmo API refused because the budget was exhausted.",
+		}),
+	}
+}
+
+// Record accounts for a request made to the API.
+func (b *Budget) Record() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock()
+	b.prune(now)
+	b.requests = append(b.requests, now)
+}
+
+// Allow returns true, if there is budget left for another request. Refused requests are counted.
+func (b *Budget) Allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.delay(b.clock()) > 0 {
+		b.refused.Inc()
+		return false
+	}
+
+	return true
+}
+
+// Wait blocks until there is budget left for another request or the context is done.
+// It is meant for heavy operations, which should not fail just because the budget is temporarily exhausted.
+func (b *Budget) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	for {
+		b.lock.Lock()
+		delay := b.delay(b.clock())
+		b.lock.Unlock()
+
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (b *Budget) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.remainingDesc
+	ch <- b.limitDesc
+	b.refused.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (b *Budget) Collect(ch chan<- prometheus.Metric) {
+	b.lock.Lock()
+	now := b.clock()
+	b.prune(now)
+	for _, limit := range b.limits {
+		window := formatWindow(limit.Window)
+		remaining := limit.Requests - b.countSince(now.Add(-limit.Window))
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		ch <- prometheus.MustNewConstMetric(b.remainingDesc, prometheus.GaugeValue, float64(remaining), window)
+		ch <- prometheus.MustNewConstMetric(b.limitDesc, prometheus.GaugeValue, float64(limit.Requests), window)
+	}
+	b.lock.Unlock()
+
+	b.refused.Collect(ch)
+}
+
+// delay returns how long it takes until all limits allow another request. Needs to be called with the lock held.
+func (b *Budget) delay(now time.Time) time.Duration {
+	var result time.Duration
+	for _, limit := range b.limits {
+		if b.countSince(now.Add(-limit.Window)) < limit.Requests {
+			continue
+		}
+
+		// The request which needs to leave the window, so that the count drops below the limit.
+		oldest := b.requests[len(b.requests)-limit.Requests]
+		if wait := oldest.Add(limit.Window).Sub(now); wait > result {
+			result = wait
+		}
+	}
+
+	return result
+}
+
+// countSince returns the number of requests made after the provided time. Needs to be called with the lock held.
+func (b *Budget) countSince(since time.Time) int {
+	count := 0
+	for i := len(b.requests) - 1; i >= 0; i-- {
+		if !b.requests[i].After(since) {
+			break
+		}
+		count++
+	}
+
+	return count
+}
+
+// prune removes requests, which are outside all windows. Needs to be called with the lock held.
+func (b *Budget) prune(now time.Time) {
+	var longest time.Duration
+	for _, limit := range b.limits {
+		if limit.Window > longest {
+			longest = limit.Window
+		}
+	}
+
+	since := now.Add(-longest)
+	keep := 0
+	for keep < len(b.requests) && !b.requests[keep].After(since) {
+		keep++
+	}
+	b.requests = b.requests[keep:]
+}
+
+// formatWindow formats a duration without trailing zero units, so that for example one hour is "1h" instead of "1h0m0s".
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
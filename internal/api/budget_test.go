@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/oauth2"
+)
+
+func TestLimitsSet(t *testing.T) {
+	tt := []struct {
+		desc       string
+		value      string
+		wantLimits Limits
+		wantString string
+		wantErr    bool
+	}{
+		{
+			desc:  "success",
+			value: "50/10s, 500/1h",
+			wantLimits: Limits{
+				{Requests: 50, Window: 10 * time.Second},
+				{Requests: 500, Window: time.Hour},
+			},
+			wantString: "50/10s,500/1h",
+		},
+		{
+			desc:  "mixed units",
+			value: "10/90s,20/10m",
+			wantLimits: Limits{
+				{Requests: 10, Window: 90 * time.Second},
+				{Requests: 20, Window: 10 * time.Minute},
+			},
+			wantString: "10/1m30s,20/10m",
+		},
+		{
+			desc:       "empty",
+			value:      "",
+			wantLimits: Limits{},
+			wantString: "",
+		},
+		{
+			desc:    "no separator",
+			value:   "50",
+			wantErr: true,
+		},
+		{
+			desc:    "invalid requests",
+			value:   "many/10s",
+			wantErr: true,
+		},
+		{
+			desc:    "invalid window",
+			value:   "50/soon",
+			wantErr: true,
+		},
+		{
+			desc:    "zero requests",
+			value:   "0/10s",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var limits Limits
+			err := limits.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if diff := cmp.Diff(limits, tc.wantLimits); diff != "" {
+				t.Errorf("limits differ: -got+want\n%s", diff)
+			}
+
+			if limits.String() != tc.wantString {
+				t.Errorf("got string %q, want %q", limits.String(), tc.wantString)
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Unix(3600, 0)
+	budget := NewBudget(Limits{
+		{Requests: 2, Window: 10 * time.Second},
+		{Requests: 3, Window: time.Minute},
+	})
+	budget.clock = func() time.Time {
+		return now
+	}
+
+	step := func(d time.Duration, wantAllow bool) {
+		t.Helper()
+
+		now = now.Add(d)
+		allow := budget.Allow()
+		if allow != wantAllow {
+			t.Fatalf("at %s: got allow %v, want %v", now, allow, wantAllow)
+		}
+
+		if allow {
+			budget.Record()
+		}
+	}
+
+	step(0, true)
+	step(time.Second, true)
+	step(time.Second, false)
+	step(9*time.Second, true)
+	step(time.Second, false)
+	step(50*time.Second, true)
+
+	budget.lock.Lock()
+	delay := budget.delay(now)
+	budget.lock.Unlock()
+	if delay != 0 {
+		t.Errorf("got delay %s, want none", delay)
+	}
+
+	wantMetrics := `# HELP netatmo_exporter_api_budget_limit Number of requests to the NetAtmo API allowed in the sliding window.
+# TYPE netatmo_exporter_api_budget_limit gauge
+netatmo_exporter_api_budget_limit{window="10s"} 2
+netatmo_exporter_api_budget_limit{window="1m"} 3
+# HELP netatmo_exporter_api_budget_refused_total Number of requests to the NetAtmo API refused because the budget was exhausted.
+# TYPE netatmo_exporter_api_budget_refused_total counter
+netatmo_exporter_api_budget_refused_total 2
+# HELP netatmo_exporter_api_budget_remaining Number of requests to the NetAtmo API left in the sliding window.
+# TYPE netatmo_exporter_api_budget_remaining gauge
+netatmo_exporter_api_budget_remaining{window="10s"} 1
+netatmo_exporter_api_budget_remaining{window="1m"} 1
+`
+	if err := testutil.CollectAndCompare(budget, strings.NewReader(wantMetrics)); err != nil {
+		t.Errorf("metrics differ: %s", err)
+	}
+}
+
+func TestBudgetWait(t *testing.T) {
+	budget := NewBudget(Limits{
+		{Requests: 1, Window: time.Hour},
+	})
+
+	if err := budget.Wait(context.Background()); err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+	budget.Record()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := budget.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %q, want %q", err, context.DeadlineExceeded)
+	}
+
+	var nilBudget *Budget
+	if err := nilBudget.Wait(context.Background()); err != nil {
+		t.Errorf("got error %q from nil budget, want none", err)
+	}
+}
+
+func TestClientReadBudget(t *testing.T) {
+	server := fakeNetatmoServer()
+	defer server.Close()
+
+	budget := NewBudget(Limits{
+		{Requests: 1, Window: time.Hour},
+	})
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
+		Transport: NewTransport(nil, budget),
+	})
+
+	client := NewClient(ctx, func() (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "access-token"}, nil
+	}, budget)
+	client.deviceURL = server.URL + "/api/getstationsdata"
+
+	if _, err := client.Read(); err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	if _, err := client.Read(); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("got error %q, want %q", err, ErrBudgetExhausted)
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"golang.org/x/oauth2"
+)
+
+const (
+	deviceURL = "https://api.netatmo.com/api/getstationsdata"
+)
+
+// Error is returned when the NetAtmo API responds with a non-ok HTTP status.
+type Error struct {
+	StatusCode int
+	Code       int
+	Message    string
+	Body       string
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("got error %d: %s (HTTP status %d)", e.Code, e.Message, e.StatusCode)
+	}
+
+	return fmt.Sprintf("got non-ok HTTP status %d: %s", e.StatusCode, e.Body)
+}
+
+// TokenFunc provides the current token used for authenticating against the NetAtmo API.
+type TokenFunc func() (*oauth2.Token, error)
+
+// Token implements oauth2.TokenSource.
+func (f TokenFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+// Client reads weather station data from the NetAtmo API.
+// The authentication is still handled by the netatmo library, the client only uses the tokens it provides.
+type Client struct {
+	httpClient *http.Client
+	budget     *Budget
+	deviceURL  string
+}
+
+// NewClient creates a new Client using the provided token function for authentication.
+// The context can be used to provide a custom base HTTP client using oauth2.HTTPClient.
+// If a budget is provided, reads are refused while the budget is exhausted.
+func NewClient(ctx context.Context, tokenFunc TokenFunc, budget *Budget) *Client {
+	return &Client{
+		httpClient: oauth2.NewClient(ctx, tokenFunc),
+		budget:     budget,
+		deviceURL:  deviceURL,
+	}
+}
+
+// Endpoint returns the URL of the API endpoint used for reading the station data.
+func (c *Client) Endpoint() string {
+	return c.deviceURL
+}
+
+// Read retrieves the current data of all weather stations accessible by the user.
+// It returns ErrBudgetExhausted without contacting the API, if the budget does not allow another request.
+func (c *Client) Read() (*DeviceCollection, error) {
+	if !c.budget.Allow() {
+		return nil, ErrBudgetExhausted
+	}
+
+	data := url.Values{"app_type": {"app_station"}}
+
+	req, err := http.NewRequest(http.MethodGet, c.deviceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = data.Encode()
+
+	resp, err := c.httpClient.Do(req)
+	switch {
+	case errors.Is(err, netatmo.ErrNotAuthenticated):
+		return nil, netatmo.ErrNotAuthenticated
+	case err != nil:
+		return nil, err
+	default:
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		buf := &bytes.Buffer{}
+		if _, err := io.Copy(buf, resp.Body); err != nil {
+			return nil, fmt.Errorf("error reading body for status code %d: %w", resp.StatusCode, err)
+		}
+
+		apiErr := &Error{
+			StatusCode: resp.StatusCode,
+			Body:       buf.String(),
+		}
+
+		var errResp netatmo.ErrorResponse
+		if err := json.Unmarshal(buf.Bytes(), &errResp); err == nil {
+			apiErr.Code = errResp.Error.Code
+			apiErr.Message = errResp.Error.Message
+		}
+
+		return nil, apiErr
+	}
+
+	result := &DeviceCollection{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
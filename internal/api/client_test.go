@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+const testResponse = `{
+  "body": {
+    "devices": [
+      {
+        "_id": "aa:bb:cc:dd:ee:f0",
+        "module_name": "Living Room",
+        "station_name": "Home (Living Room)",
+        "home_name": "Home",
+        "reachable": true,
+        "last_setup": 1000,
+        "last_status_store": 3510,
+        "dashboard_data": {
+          "Temperature": 23,
+          "time_utc": 3500
+        },
+        "modules": [
+          {
+            "_id": "aa:bb:cc:dd:ee:f1",
+            "module_name": "Outside",
+            "reachable": false,
+            "last_seen": 3505,
+            "last_message": 3506,
+            "dashboard_data": {
+              "Temperature": 5,
+              "time_utc": 3501
+            }
+          }
+        ]
+      }
+    ]
+  },
+  "status": "ok"
+}`
+
+func TestClientRead(t *testing.T) {
+	validToken := func() (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "access-token"}, nil
+	}
+
+	tt := []struct {
+		desc       string
+		tokenFunc  TokenFunc
+		status     int
+		body       string
+		wantData   func() *DeviceCollection
+		wantErr    error
+		wantErrMsg string
+	}{
+		{
+			desc:      "success",
+			tokenFunc: validToken,
+			status:    http.StatusOK,
+			body:      testResponse,
+			wantData: func() *DeviceCollection {
+				dc := &DeviceCollection{}
+				dc.Body.Devices = []*Device{
+					{
+						Device: netatmo.Device{
+							ID:          "aa:bb:cc:dd:ee:f0",
+							ModuleName:  "Living Room",
+							StationName: "Home (Living Room)",
+							HomeName:    "Home",
+							DashboardData: netatmo.DashboardData{
+								Temperature: float32Ptr(23),
+								LastMeasure: int64Ptr(3500),
+							},
+						},
+						Reachable:       boolPtr(true),
+						LastSetup:       int64Ptr(1000),
+						LastStatusStore: int64Ptr(3510),
+						LinkedModules: []*Device{
+							{
+								Device: netatmo.Device{
+									ID:         "aa:bb:cc:dd:ee:f1",
+									ModuleName: "Outside",
+									DashboardData: netatmo.DashboardData{
+										Temperature: float32Ptr(5),
+										LastMeasure: int64Ptr(3501),
+									},
+								},
+								Reachable:   boolPtr(false),
+								LastSeen:    int64Ptr(3505),
+								LastMessage: int64Ptr(3506),
+							},
+						},
+					},
+				}
+				return dc
+			},
+		},
+		{
+			desc: "not authenticated",
+			tokenFunc: func() (*oauth2.Token, error) {
+				return nil, netatmo.ErrNotAuthenticated
+			},
+			wantErr:    netatmo.ErrNotAuthenticated,
+			wantErrMsg: "no token available",
+		},
+		{
+			desc:       "api error",
+			tokenFunc:  validToken,
+			status:     http.StatusForbidden,
+			body:       `{"error":{"code":3,"message":"Access token expired"}}`,
+			wantErrMsg: "got error 3: Access token expired (HTTP status 403)",
+		},
+		{
+			desc:       "unknown error",
+			tokenFunc:  validToken,
+			status:     http.StatusInternalServerError,
+			body:       `{}`,
+			wantErrMsg: "got non-ok HTTP status 500: {}",
+		},
+		{
+			desc:       "plain text error",
+			tokenFunc:  validToken,
+			status:     http.StatusBadGateway,
+			body:       `bad gateway`,
+			wantErrMsg: "got non-ok HTTP status 502: bad gateway",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer access-token" {
+					t.Errorf("got authorization %q", r.Header.Get("Authorization"))
+				}
+
+				if r.URL.Query().Get("app_type") != "app_station" {
+					t.Errorf("got app_type %q", r.URL.Query().Get("app_type"))
+				}
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client := NewClient(context.Background(), tc.tokenFunc, nil)
+			client.deviceURL = server.URL
+
+			data, err := client.Read()
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if tc.wantErrMsg != "" {
+				if err == nil || err.Error() != tc.wantErrMsg {
+					t.Errorf("got error %q, want %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if diff := cmp.Diff(data, tc.wantData()); diff != "" {
+				t.Errorf("data differs: -got+want\n%s", diff)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func float32Ptr(f float32) *float32 {
+	return &f
+}
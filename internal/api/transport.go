@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsPrefix = "netatmo_exporter_api_"
+
+	statusTransportError = "error"
+)
+
+// Transport is a http.RoundTripper, which records metrics about the requests made to the NetAtmo API.
+// It implements prometheus.Collector, so that the metrics can be registered.
+type Transport struct {
+	base   http.RoundTripper
+	budget *Budget
+	clock  func() time.Time
+
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	apiErrors    *prometheus.CounterVec
+}
+
+var _ http.RoundTripper = &Transport{}
+var _ prometheus.Collector = &Transport{}
+
+// NewTransport creates a new instrumented Transport using the provided base. If base is nil, http.DefaultTransport is used.
+// All requests are recorded in the budget, which can be nil.
+func NewTransport(base http.RoundTripper, budget *Budget) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		base:   base,
+		budget: budget,
+		clock:  time.Now,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricsPrefix + "requests_total",
+			Help: "Number of requests made to the NetAtmo API by endpoint and HTTP status code.",
+		}, []string{"endpoint", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    metricsPrefix + "request_duration_seconds",
+			Help:    "Duration of requests made to the NetAtmo API until the response headers are received.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    metricsPrefix + "response_size_bytes",
+			Help:    "Size of the response bodies returned by the NetAtmo API.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 6),
+		}, []string{"endpoint"}),
+		apiErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricsPrefix + "errors_total",
+			Help: "Number of errors returned by the NetAtmo API by endpoint and error code.",
+		}, []string{"endpoint", "code"}),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := req.URL.Path
+	start := t.clock()
+	t.budget.Record()
+
+	resp, err := t.base.RoundTrip(req)
+	t.duration.WithLabelValues(endpoint).Observe(t.clock().Sub(start).Seconds())
+	if err != nil {
+		t.requests.WithLabelValues(endpoint, statusTransportError).Inc()
+		return nil, err
+	}
+	t.requests.WithLabelValues(endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		t.responseSize.WithLabelValues(endpoint).Observe(float64(len(body)))
+		t.apiErrors.WithLabelValues(endpoint, parseErrorCode(body)).Inc()
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+
+	resp.Body = &countingBody{
+		ReadCloser: resp.Body,
+		observer:   t.responseSize.WithLabelValues(endpoint),
+	}
+	return resp, nil
+}
+
+// Describe implements prometheus.Collector.
+func (t *Transport) Describe(ch chan<- *prometheus.Desc) {
+	t.requests.Describe(ch)
+	t.duration.Describe(ch)
+	t.responseSize.Describe(ch)
+	t.apiErrors.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (t *Transport) Collect(ch chan<- prometheus.Metric) {
+	t.requests.Collect(ch)
+	t.duration.Collect(ch)
+	t.responseSize.Collect(ch)
+	t.apiErrors.Collect(ch)
+}
+
+// parseErrorCode extracts the error code from an error response.
+// The data API returns a numeric code, while the OAuth endpoints return an error string.
+func parseErrorCode(body []byte) string {
+	var apiError struct {
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiError); err == nil && apiError.Error.Code != 0 {
+		return strconv.Itoa(apiError.Error.Code)
+	}
+
+	var oauthError struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &oauthError); err == nil && oauthError.Error != "" {
+		return oauthError.Error
+	}
+
+	return "unknown"
+}
+
+// countingBody counts the bytes read from a response body and reports them once the body is closed.
+type countingBody struct {
+	io.ReadCloser
+	observer prometheus.Observer
+	size     int
+	closed   bool
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.observer.Observe(float64(b.size))
+	}
+
+	return b.ReadCloser.Close()
+}
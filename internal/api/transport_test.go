@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/oauth2"
+)
+
+func fakeNetatmoServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/getstationsdata", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":2,"message":"Invalid access token"}}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"body":{"devices":[]},"status":"ok"}`))
+	})
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+	})
+	mux.HandleFunc("/api/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`internal error`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestTransport(t *testing.T) {
+	server := fakeNetatmoServer()
+	defer server.Close()
+
+	transport := NewTransport(nil, nil)
+	httpClient := &http.Client{Transport: transport}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+
+	validClient := NewClient(ctx, func() (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "access-token"}, nil
+	}, nil)
+	validClient.deviceURL = server.URL + "/api/getstationsdata"
+	for i := 0; i < 2; i++ {
+		if _, err := validClient.Read(); err != nil {
+			t.Fatalf("got error %q, want none", err)
+		}
+	}
+
+	invalidClient := NewClient(ctx, func() (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "invalid-token"}, nil
+	}, nil)
+	invalidClient.deviceURL = server.URL + "/api/getstationsdata"
+	_, err := invalidClient.Read()
+	if err == nil || err.Error() != "got error 2: Invalid access token (HTTP status 403)" {
+		t.Errorf("got error %q", err)
+	}
+
+	oauthConfig := &oauth2.Config{
+		Endpoint: oauth2.Endpoint{
+			TokenURL:  server.URL + "/oauth2/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+	if _, err := oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: "refresh-token"}).Token(); err == nil {
+		t.Error("expected error from token refresh")
+	}
+
+	resp, err := httpClient.Get(server.URL + "/api/broken")
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+	resp.Body.Close()
+
+	wantMetrics := `# HELP netatmo_exporter_api_errors_total Number of errors returned by the NetAtmo API by endpoint and error code.
+# TYPE netatmo_exporter_api_errors_total counter
+netatmo_exporter_api_errors_total{code="2",endpoint="/api/getstationsdata"} 1
+netatmo_exporter_api_errors_total{code="invalid_grant",endpoint="/oauth2/token"} 1
+netatmo_exporter_api_errors_total{code="unknown",endpoint="/api/broken"} 1
+# HELP netatmo_exporter_api_requests_total Number of requests made to the NetAtmo API by endpoint and HTTP status code.
+# TYPE netatmo_exporter_api_requests_total counter
+netatmo_exporter_api_requests_total{endpoint="/api/broken",status="500"} 1
+netatmo_exporter_api_requests_total{endpoint="/api/getstationsdata",status="200"} 2
+netatmo_exporter_api_requests_total{endpoint="/api/getstationsdata",status="403"} 1
+netatmo_exporter_api_requests_total{endpoint="/oauth2/token",status="400"} 1
+# HELP netatmo_exporter_api_response_size_bytes Size of the response bodies returned by the NetAtmo API.
+# TYPE netatmo_exporter_api_response_size_bytes histogram
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/broken",le="256"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/broken",le="1024"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/broken",le="4096"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/broken",le="16384"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/broken",le="65536"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/broken",le="262144"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/broken",le="+Inf"} 1
+netatmo_exporter_api_response_size_bytes_sum{endpoint="/api/broken"} 14
+netatmo_exporter_api_response_size_bytes_count{endpoint="/api/broken"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/getstationsdata",le="256"} 3
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/getstationsdata",le="1024"} 3
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/getstationsdata",le="4096"} 3
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/getstationsdata",le="16384"} 3
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/getstationsdata",le="65536"} 3
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/getstationsdata",le="262144"} 3
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/api/getstationsdata",le="+Inf"} 3
+netatmo_exporter_api_response_size_bytes_sum{endpoint="/api/getstationsdata"} 127
+netatmo_exporter_api_response_size_bytes_count{endpoint="/api/getstationsdata"} 3
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/oauth2/token",le="256"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/oauth2/token",le="1024"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/oauth2/token",le="4096"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/oauth2/token",le="16384"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/oauth2/token",le="65536"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/oauth2/token",le="262144"} 1
+netatmo_exporter_api_response_size_bytes_bucket{endpoint="/oauth2/token",le="+Inf"} 1
+netatmo_exporter_api_response_size_bytes_sum{endpoint="/oauth2/token"} 25
+netatmo_exporter_api_response_size_bytes_count{endpoint="/oauth2/token"} 1
+`
+
+	metricNames := []string{
+		"netatmo_exporter_api_errors_total",
+		"netatmo_exporter_api_requests_total",
+		"netatmo_exporter_api_response_size_bytes",
+	}
+	if err := testutil.CollectAndCompare(transport, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+
+	if count := testutil.CollectAndCount(transport, "netatmo_exporter_api_request_duration_seconds"); count != 3 {
+		t.Errorf("got %d duration series, want 3", count)
+	}
+}
+
+func TestParseErrorCode(t *testing.T) {
+	tt := []struct {
+		desc     string
+		body     string
+		wantCode string
+	}{
+		{
+			desc:     "api error",
+			body:     `{"error":{"code":26,"message":"User usage reached"}}`,
+			wantCode: "26",
+		},
+		{
+			desc:     "oauth error",
+			body:     `{"error":"invalid_client"}`,
+			wantCode: "invalid_client",
+		},
+		{
+			desc:     "no json",
+			body:     `Bad Gateway`,
+			wantCode: "unknown",
+		},
+		{
+			desc:     "empty",
+			body:     ``,
+			wantCode: "unknown",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			code := parseErrorCode([]byte(tc.body))
+			if code != tc.wantCode {
+				t.Errorf("got code %q, want %q", code, tc.wantCode)
+			}
+		})
+	}
+}
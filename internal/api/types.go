@@ -0,0 +1,50 @@
+package api
+
+import (
+	netatmo "github.com/exzz/netatmo-api-go"
+)
+
+// DeviceCollection contains the weather stations returned by the NetAtmo API.
+type DeviceCollection struct {
+	Body struct {
+		Devices []*Device `json:"devices"`
+	}
+}
+
+// Devices returns the list of stations contained in the collection.
+func (dc *DeviceCollection) Devices() []*Device {
+	if dc == nil {
+		return nil
+	}
+
+	return dc.Body.Devices
+}
+
+// Device extends the device information of the netatmo library with fields it does not parse.
+// It is used both for the station itself and its linked modules.
+type Device struct {
+	netatmo.Device
+
+	// LinkedModules shadows the field of the embedded device, so that the modules contain the extended information as well.
+	LinkedModules []*Device `json:"modules"`
+
+	Reachable       *bool  `json:"reachable,omitempty"`
+	LastSetup       *int64 `json:"last_setup,omitempty"`
+	LastStatusStore *int64 `json:"last_status_store,omitempty"`
+	LastMessage     *int64 `json:"last_message,omitempty"`
+	LastSeen        *int64 `json:"last_seen,omitempty"`
+
+	BatteryVP    *int32  `json:"battery_vp,omitempty"`
+	BatteryState *string `json:"battery_state,omitempty"`
+
+	Place *Place `json:"place,omitempty"`
+}
+
+// Place contains information about the location of a station.
+type Place struct {
+	Altitude *float64  `json:"altitude,omitempty"`
+	City     string    `json:"city,omitempty"`
+	Country  string    `json:"country,omitempty"`
+	Timezone string    `json:"timezone,omitempty"`
+	Location []float64 `json:"location,omitempty"`
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+// batteryStates contains the battery states reported by NetAtmo, ordered from best to worst.
+var batteryStates = []string{
+	"full",
+	"high",
+	"medium",
+	"low",
+	"very_low",
+}
+
+// batteryThresholds contains the minimum voltage in millivolts for the "full", "high", "medium" and "low" states per module type.
+// The values are taken from the NetAtmo API documentation.
+var batteryThresholds = map[string][4]int32{
+	"NAModule1": {5500, 5000, 4500, 4000},
+	"NAModule2": {5590, 5180, 4770, 4360},
+	"NAModule3": {5500, 5000, 4500, 4000},
+	"NAModule4": {5640, 5280, 4920, 4560},
+}
+
+// batteryState returns the battery state of the device.
+// If the API does not report a state, it is derived from the battery voltage using the documented thresholds.
+// An empty string is returned if the state can not be determined.
+func batteryState(device *api.Device) string {
+	if device.BatteryState != nil && *device.BatteryState != "" {
+		return strings.ReplaceAll(strings.ToLower(*device.BatteryState), " ", "_")
+	}
+
+	if device.BatteryVP == nil {
+		return ""
+	}
+
+	thresholds, ok := batteryThresholds[device.Type]
+	if !ok {
+		return ""
+	}
+
+	for i, threshold := range thresholds {
+		if *device.BatteryVP >= threshold {
+			return batteryStates[i]
+		}
+	}
+
+	return batteryStates[len(batteryStates)-1]
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"testing"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestBatteryState(t *testing.T) {
+	tt := []struct {
+		desc       string
+		moduleType string
+		voltage    *int32
+		state      *string
+		wantState  string
+	}{
+		{
+			desc:       "no data",
+			moduleType: "NAModule1",
+			wantState:  "",
+		},
+		{
+			desc:       "reported state",
+			moduleType: "NAModule1",
+			voltage:    int32Ptr(6000),
+			state:      stringPtr("Very Low"),
+			wantState:  "very_low",
+		},
+		{
+			desc:       "unknown module type",
+			moduleType: "NAMain",
+			voltage:    int32Ptr(6000),
+			wantState:  "",
+		},
+		{
+			desc:       "outdoor full",
+			moduleType: "NAModule1",
+			voltage:    int32Ptr(5500),
+			wantState:  "full",
+		},
+		{
+			desc:       "outdoor medium",
+			moduleType: "NAModule1",
+			voltage:    int32Ptr(4999),
+			wantState:  "medium",
+		},
+		{
+			desc:       "wind gauge medium",
+			moduleType: "NAModule2",
+			voltage:    int32Ptr(4800),
+			wantState:  "medium",
+		},
+		{
+			desc:       "wind gauge low",
+			moduleType: "NAModule2",
+			voltage:    int32Ptr(4400),
+			wantState:  "low",
+		},
+		{
+			desc:       "rain gauge high",
+			moduleType: "NAModule3",
+			voltage:    int32Ptr(5100),
+			wantState:  "high",
+		},
+		{
+			desc:       "indoor very low",
+			moduleType: "NAModule4",
+			voltage:    int32Ptr(4500),
+			wantState:  "very_low",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			device := &api.Device{
+				Device: netatmo.Device{
+					Type: tc.moduleType,
+				},
+				BatteryVP:    tc.voltage,
+				BatteryState: tc.state,
+			}
+
+			state := batteryState(device)
+			if state != tc.wantState {
+				t.Errorf("got state %q, want %q", state, tc.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"time"
+)
+
+// CachePolicy decides what happens with the cached data, when refreshing it fails.
+type CachePolicy string
+
+const (
+	// CachePolicyServeForever serves the last good data, no matter how old it is.
+	CachePolicyServeForever CachePolicy = "serve-forever"
+	// CachePolicyServeStale serves the last good data until it is older than the maximum age.
+	// The data is kept, so that it can still be inspected using the debug handlers.
+	CachePolicyServeStale CachePolicy = "serve-stale"
+	// CachePolicyDrop discards the cached data once it is older than the maximum age.
+	CachePolicyDrop CachePolicy = "drop"
+)
+
+// Valid returns true if the cache policy is known.
+func (p CachePolicy) Valid() bool {
+	switch p {
+	case CachePolicyServeForever, CachePolicyServeStale, CachePolicyDrop:
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	cacheAgeDesc = newDesc(
+		prefix+"cache_age_seconds",
+		"Age of the cached data in seconds. Only present while there is cached data.",
+		nil)
+	cacheServingStaleDesc = newDesc(
+		prefix+"cache_serving_stale",
+		"One if the cached data is served although the latest refresh was not successful.",
+		nil)
+)
+
+// expireCache discards the cached data if the cache policy requires it. Needs to be called with the cache lock held.
+func (c *NetatmoCollector) expireCache(now time.Time) {
+	if c.CachePolicy != CachePolicyDrop || c.cachedData == nil {
+		return
+	}
+
+	if now.Sub(c.cacheTimestamp) > c.CacheMaxAge {
+		c.Log.Warnf("Dropping cached data, because it is older than %s.", c.CacheMaxAge)
+		c.cachedData = nil
+	}
+}
+
+// serveCache decides whether the cached data is exported and whether it is stale. The data is stale, when the
+// last completed refresh failed. Running refreshes and refreshes skipped because of the API budget do not count.
+// Needs to be called with the cache lock held.
+func (c *NetatmoCollector) serveCache(now time.Time) (serve, stale bool) {
+	if c.cachedData == nil {
+		return false, false
+	}
+
+	if c.CachePolicy == CachePolicyServeStale && now.Sub(c.cacheTimestamp) > c.CacheMaxAge {
+		return false, false
+	}
+
+	c.refreshLock.RLock()
+	defer c.refreshLock.RUnlock()
+
+	return true, c.lastRefreshError != nil
+}
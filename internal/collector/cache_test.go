@@ -0,0 +1,231 @@
+package collector
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestCachePolicy(t *testing.T) {
+	testData := &api.DeviceCollection{}
+	testData.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(23),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+		},
+	}
+	testError := errors.New("test error")
+	temperature := `# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 23
+`
+
+	tt := []struct {
+		desc            string
+		policy          CachePolicy
+		refreshInterval time.Duration
+		failRefresh     bool
+		skipRefresh     bool
+		runningRefresh  bool
+		collectTime     time.Time
+		wantDropped     bool
+		wantMetrics     string
+	}{
+		{
+			desc:        "serve forever, refresh ok",
+			policy:      CachePolicyServeForever,
+			collectTime: time.Unix(3900, 0),
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 300
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 0
+` + temperature,
+		},
+		{
+			desc:        "serve forever, refresh failed",
+			policy:      CachePolicyServeForever,
+			failRefresh: true,
+			collectTime: time.Unix(10800, 0),
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 7200
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 1
+` + temperature,
+		},
+		{
+			desc:            "serve forever, refresh running",
+			policy:          CachePolicyServeForever,
+			refreshInterval: 5 * time.Minute,
+			runningRefresh:  true,
+			collectTime:     time.Unix(3900, 0),
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 300
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 0
+` + temperature,
+		},
+		{
+			desc:            "serve forever, refresh skipped for budget",
+			policy:          CachePolicyServeForever,
+			refreshInterval: 5 * time.Minute,
+			skipRefresh:     true,
+			collectTime:     time.Unix(4000, 0),
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 400
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 0
+` + temperature,
+		},
+		{
+			desc:        "serve stale, within max age",
+			policy:      CachePolicyServeStale,
+			failRefresh: true,
+			collectTime: time.Unix(4800, 0),
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 1200
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 1
+` + temperature,
+		},
+		{
+			desc:        "serve stale, exceeded max age",
+			policy:      CachePolicyServeStale,
+			failRefresh: true,
+			collectTime: time.Unix(10800, 0),
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 7200
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 0
+`,
+		},
+		{
+			desc:        "drop, within max age",
+			policy:      CachePolicyDrop,
+			failRefresh: true,
+			collectTime: time.Unix(4800, 0),
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 1200
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 1
+` + temperature,
+		},
+		{
+			desc:        "drop, exceeded max age",
+			policy:      CachePolicyDrop,
+			failRefresh: true,
+			collectTime: time.Unix(10800, 0),
+			wantDropped: true,
+			wantMetrics: `# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 0
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			refreshInterval := tc.refreshInterval
+			if refreshInterval == 0 {
+				refreshInterval = 100 * time.Hour
+			}
+
+			var lock sync.Mutex
+			now := time.Unix(3600, 0)
+			c := New(logrus.New(), func() (*api.DeviceCollection, error) {
+				return testData, nil
+			}, refreshInterval, 100*time.Hour, Names{})
+			c.CachePolicy = tc.policy
+			c.CacheMaxAge = 30 * time.Minute
+			c.clock = func() time.Time {
+				lock.Lock()
+				defer lock.Unlock()
+
+				return now
+			}
+			c.RefreshData(c.clock())
+
+			if tc.failRefresh {
+				now = time.Unix(4200, 0)
+				c.ReadFunction = func() (*api.DeviceCollection, error) {
+					return nil, testError
+				}
+				c.RefreshData(now)
+			}
+
+			if tc.skipRefresh {
+				now = time.Unix(3900, 0)
+				c.ReadFunction = func() (*api.DeviceCollection, error) {
+					return nil, api.ErrBudgetExhausted
+				}
+				c.RefreshData(now)
+			}
+
+			lock.Lock()
+			now = tc.collectTime
+			lock.Unlock()
+
+			if tc.runningRefresh {
+				started := make(chan struct{})
+				release := make(chan struct{})
+				t.Cleanup(func() {
+					close(release)
+				})
+				c.ReadFunction = func() (*api.DeviceCollection, error) {
+					close(started)
+					<-release
+					return testData, nil
+				}
+
+				// The first scrape starts the refresh, which blocks until the test is finished.
+				testutil.CollectAndCount(c)
+				<-started
+			}
+
+			metricNames := []string{
+				"netatmo_cache_age_seconds",
+				"netatmo_cache_serving_stale",
+				"netatmo_sensor_temperature_celsius",
+			}
+			if err := testutil.CollectAndCompare(c, strings.NewReader(tc.wantMetrics), metricNames...); err != nil {
+				t.Error(err)
+			}
+
+			if dropped := c.cachedData == nil; dropped != tc.wantDropped {
+				t.Errorf("got dropped %v, want %v", dropped, tc.wantDropped)
+			}
+		})
+	}
+}
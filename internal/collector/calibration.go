@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+// Calibration is a linear correction of a measurement. The calibrated value is value * Factor + Offset.
+// A factor of zero is treated as not set and does not scale the value.
+type Calibration struct {
+	Offset float64 `yaml:"offset"`
+	Factor float64 `yaml:"factor"`
+}
+
+// Apply returns the calibrated value.
+func (c Calibration) Apply(value float64) float64 {
+	if c.Factor != 0 {
+		value *= c.Factor
+	}
+
+	return value + c.Offset
+}
+
+// ModuleCalibration contains the calibration of the measurements of a module. Measurements without a calibration are not changed.
+type ModuleCalibration struct {
+	Temperature      *Calibration `yaml:"temperature"`
+	Humidity         *Calibration `yaml:"humidity"`
+	CO2              *Calibration `yaml:"co2"`
+	Noise            *Calibration `yaml:"noise"`
+	Pressure         *Calibration `yaml:"pressure"`
+	AbsolutePressure *Calibration `yaml:"absolute_pressure"`
+	WindStrength     *Calibration `yaml:"wind_strength"`
+	Rain             *Calibration `yaml:"rain"`
+}
+
+// apply returns a copy of the data with the calibrated values.
+// Measurements reported as whole numbers by NetAtmo are rounded to whole numbers again.
+func (m ModuleCalibration) apply(data netatmo.DashboardData) netatmo.DashboardData {
+	data.Temperature = calibrateFloat(m.Temperature, data.Temperature)
+	data.Humidity = calibrateInt(m.Humidity, data.Humidity)
+	data.CO2 = calibrateInt(m.CO2, data.CO2)
+	data.Noise = calibrateInt(m.Noise, data.Noise)
+	data.Pressure = calibrateFloat(m.Pressure, data.Pressure)
+	data.AbsolutePressure = calibrateFloat(m.AbsolutePressure, data.AbsolutePressure)
+	data.WindStrength = calibrateInt(m.WindStrength, data.WindStrength)
+	data.Rain = calibrateFloat(m.Rain, data.Rain)
+
+	return data
+}
+
+func calibrateFloat(calibration *Calibration, value *float32) *float32 {
+	if calibration == nil || value == nil {
+		return value
+	}
+
+	result := float32(calibration.Apply(float64(*value)))
+	return &result
+}
+
+func calibrateInt(calibration *Calibration, value *int32) *int32 {
+	if calibration == nil || value == nil {
+		return value
+	}
+
+	result := int32(math.Round(calibration.Apply(float64(*value))))
+	return &result
+}
+
+var (
+	tempRawDesc             = newRawDesc(tempDesc)
+	humidityRawDesc         = newRawDesc(humidityDesc)
+	cotwoRawDesc            = newRawDesc(cotwoDesc)
+	noiseRawDesc            = newRawDesc(noiseDesc)
+	pressureRawDesc         = newRawDesc(pressureDesc)
+	absolutePressureRawDesc = newRawDesc(absolutePressureDesc)
+	windStrengthRawDesc     = newRawDesc(windStrengthDesc)
+	rainRawDesc             = newRawDesc(rainDesc)
+
+	pressurePascalsRawDesc             = newRawDesc(pressurePascalsDesc)
+	absolutePressurePascalsRawDesc     = newRawDesc(absolutePressurePascalsDesc)
+	windStrengthMetersPerSecondRawDesc = newRawDesc(windStrengthMetersPerSecondDesc)
+	noiseDecibelsRawDesc               = newRawDesc(noiseDecibelsDesc)
+	rainMetersRawDesc                  = newRawDesc(rainMetersDesc)
+	humidityRatioRawDesc               = newRawDesc(humidityRatioDesc)
+
+	rawDescs = []*prometheus.Desc{
+		tempRawDesc,
+		humidityRawDesc,
+		cotwoRawDesc,
+		noiseRawDesc,
+		pressureRawDesc,
+		absolutePressureRawDesc,
+		windStrengthRawDesc,
+		rainRawDesc,
+	}
+)
+
+// newRawDesc creates the descriptor for the uncalibrated values of a measurement.
+func newRawDesc(desc *prometheus.Desc) *prometheus.Desc {
+	spec := descSpecs[desc]
+	return newDesc(spec.name+"_raw", spec.help+" without calibration", spec.labels)
+}
+
+// SetCalibration configures the calibration of the measurements. The keys of the map are module IDs.
+func (c *NetatmoCollector) SetCalibration(calibration map[string]ModuleCalibration) {
+	c.calibration = make(map[string]ModuleCalibration, len(calibration))
+	for id, moduleCalibration := range calibration {
+		c.calibration[strings.ToLower(id)] = moduleCalibration
+	}
+}
+
+// calibratedData returns the data of the device with the configured calibration applied.
+func (c *NetatmoCollector) calibratedData(device *api.Device) netatmo.DashboardData {
+	calibration, ok := c.calibration[strings.ToLower(device.ID)]
+	if !ok {
+		return device.DashboardData
+	}
+
+	return calibration.apply(device.DashboardData)
+}
+
+// collectRaw sends the uncalibrated values of the calibrated measurements of a device.
+func (c *NetatmoCollector) collectRaw(ch chan<- prometheus.Metric, device *api.Device, timestamp time.Time, labels ...string) {
+	calibration, ok := c.calibration[strings.ToLower(device.ID)]
+	if !ok {
+		return
+	}
+
+	data := device.DashboardData
+	for _, m := range []struct {
+		calibration *Calibration
+		desc        *prometheus.Desc
+		value       *float64
+	}{
+		{calibration.Temperature, tempRawDesc, floatValue(data.Temperature)},
+		{calibration.Humidity, humidityRawDesc, intValue(data.Humidity)},
+		{calibration.CO2, cotwoRawDesc, intValue(data.CO2)},
+		{calibration.Noise, noiseRawDesc, intValue(data.Noise)},
+		{calibration.Pressure, pressureRawDesc, floatValue(data.Pressure)},
+		{calibration.AbsolutePressure, absolutePressureRawDesc, floatValue(data.AbsolutePressure)},
+		{calibration.WindStrength, windStrengthRawDesc, intValue(data.WindStrength)},
+		{calibration.Rain, rainRawDesc, floatValue(data.Rain)},
+	} {
+		if m.calibration == nil || m.value == nil {
+			continue
+		}
+
+		c.sendMetricAt(ch, m.desc, prometheus.GaugeValue, *m.value, timestamp, labels...)
+	}
+}
+
+func floatValue(value *float32) *float64 {
+	if value == nil {
+		return nil
+	}
+
+	result := float64(*value)
+	return &result
+}
+
+func intValue(value *int32) *float64 {
+	if value == nil {
+		return nil
+	}
+
+	result := float64(*value)
+	return &result
+}
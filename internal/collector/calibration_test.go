@@ -0,0 +1,330 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestCalibration(t *testing.T) {
+	tt := []struct {
+		desc        string
+		calibration Calibration
+		value       float64
+		want        float64
+	}{
+		{
+			desc:  "empty",
+			value: 23,
+			want:  23,
+		},
+		{
+			desc:        "offset",
+			calibration: Calibration{Offset: -0.5},
+			value:       23,
+			want:        22.5,
+		},
+		{
+			desc:        "factor",
+			calibration: Calibration{Factor: 1.5},
+			value:       10,
+			want:        15,
+		},
+		{
+			desc:        "offset and factor",
+			calibration: Calibration{Offset: 3, Factor: 2},
+			value:       10,
+			want:        23,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.calibration.Apply(tc.value); got != tc.want {
+				t.Errorf("got %f, want %f", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNetatmoCollector_CollectCalibration(t *testing.T) {
+	tt := []struct {
+		desc        string
+		device      netatmo.Device
+		calibration ModuleCalibration
+		naming      MetricNaming
+		wantMetrics string
+		metricNames []string
+	}{
+		{
+			desc: "station",
+			device: netatmo.Device{
+				Type: "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature:      float32Ptr(23),
+					Humidity:         int32Ptr(45),
+					CO2:              int32Ptr(650),
+					Noise:            int32Ptr(40),
+					Pressure:         float32Ptr(1012),
+					AbsolutePressure: float32Ptr(987),
+				},
+			},
+			calibration: ModuleCalibration{
+				Temperature:      &Calibration{Offset: -1},
+				Humidity:         &Calibration{Offset: 3},
+				CO2:              &Calibration{Factor: 1.1},
+				Noise:            &Calibration{Offset: -2},
+				Pressure:         &Calibration{Offset: 1.5},
+				AbsolutePressure: &Calibration{Offset: 1.5},
+			},
+			wantMetrics: `# HELP netatmo_sensor_absolute_pressure_mb Atmospheric pressure measurement at the altitude of the station in millibar
+# TYPE netatmo_sensor_absolute_pressure_mb gauge
+netatmo_sensor_absolute_pressure_mb{home="Home",module="Test",station="Home (Test)"} 988.5
+# HELP netatmo_sensor_absolute_pressure_mb_raw Atmospheric pressure measurement at the altitude of the station in millibar without calibration
+# TYPE netatmo_sensor_absolute_pressure_mb_raw gauge
+netatmo_sensor_absolute_pressure_mb_raw{home="Home",module="Test",station="Home (Test)"} 987
+# HELP netatmo_sensor_co2_ppm Carbondioxide measurement in parts per million
+# TYPE netatmo_sensor_co2_ppm gauge
+netatmo_sensor_co2_ppm{home="Home",module="Test",station="Home (Test)"} 715
+# HELP netatmo_sensor_co2_ppm_raw Carbondioxide measurement in parts per million without calibration
+# TYPE netatmo_sensor_co2_ppm_raw gauge
+netatmo_sensor_co2_ppm_raw{home="Home",module="Test",station="Home (Test)"} 650
+# HELP netatmo_sensor_humidity_percent Relative humidity measurement in percent
+# TYPE netatmo_sensor_humidity_percent gauge
+netatmo_sensor_humidity_percent{home="Home",module="Test",station="Home (Test)"} 48
+# HELP netatmo_sensor_humidity_percent_raw Relative humidity measurement in percent without calibration
+# TYPE netatmo_sensor_humidity_percent_raw gauge
+netatmo_sensor_humidity_percent_raw{home="Home",module="Test",station="Home (Test)"} 45
+# HELP netatmo_sensor_noise_db Noise measurement in decibels
+# TYPE netatmo_sensor_noise_db gauge
+netatmo_sensor_noise_db{home="Home",module="Test",station="Home (Test)"} 38
+# HELP netatmo_sensor_noise_db_raw Noise measurement in decibels without calibration
+# TYPE netatmo_sensor_noise_db_raw gauge
+netatmo_sensor_noise_db_raw{home="Home",module="Test",station="Home (Test)"} 40
+# HELP netatmo_sensor_pressure_mb Atmospheric pressure measurement in millibar
+# TYPE netatmo_sensor_pressure_mb gauge
+netatmo_sensor_pressure_mb{home="Home",module="Test",station="Home (Test)"} 1013.5
+# HELP netatmo_sensor_pressure_mb_raw Atmospheric pressure measurement in millibar without calibration
+# TYPE netatmo_sensor_pressure_mb_raw gauge
+netatmo_sensor_pressure_mb_raw{home="Home",module="Test",station="Home (Test)"} 1012
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Test",station="Home (Test)"} 22
+# HELP netatmo_sensor_temperature_celsius_raw Temperature measurement in celsius without calibration
+# TYPE netatmo_sensor_temperature_celsius_raw gauge
+netatmo_sensor_temperature_celsius_raw{home="Home",module="Test",station="Home (Test)"} 23
+`,
+			metricNames: []string{
+				"netatmo_sensor_absolute_pressure_mb",
+				"netatmo_sensor_absolute_pressure_mb_raw",
+				"netatmo_sensor_co2_ppm",
+				"netatmo_sensor_co2_ppm_raw",
+				"netatmo_sensor_humidity_percent",
+				"netatmo_sensor_humidity_percent_raw",
+				"netatmo_sensor_noise_db",
+				"netatmo_sensor_noise_db_raw",
+				"netatmo_sensor_pressure_mb",
+				"netatmo_sensor_pressure_mb_raw",
+				"netatmo_sensor_temperature_celsius",
+				"netatmo_sensor_temperature_celsius_raw",
+			},
+		},
+		{
+			desc: "outdoor module",
+			device: netatmo.Device{
+				Type: "NAModule1",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(5),
+					Humidity:    int32Ptr(83),
+				},
+			},
+			calibration: ModuleCalibration{
+				Temperature: &Calibration{Offset: 0.5, Factor: 1.5},
+				Humidity:    &Calibration{Factor: 1.02},
+			},
+			wantMetrics: `# HELP netatmo_sensor_humidity_percent Relative humidity measurement in percent
+# TYPE netatmo_sensor_humidity_percent gauge
+netatmo_sensor_humidity_percent{home="Home",module="Test",station="Home (Test)"} 85
+# HELP netatmo_sensor_humidity_percent_raw Relative humidity measurement in percent without calibration
+# TYPE netatmo_sensor_humidity_percent_raw gauge
+netatmo_sensor_humidity_percent_raw{home="Home",module="Test",station="Home (Test)"} 83
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Test",station="Home (Test)"} 8
+# HELP netatmo_sensor_temperature_celsius_raw Temperature measurement in celsius without calibration
+# TYPE netatmo_sensor_temperature_celsius_raw gauge
+netatmo_sensor_temperature_celsius_raw{home="Home",module="Test",station="Home (Test)"} 5
+`,
+			metricNames: []string{
+				"netatmo_sensor_humidity_percent",
+				"netatmo_sensor_humidity_percent_raw",
+				"netatmo_sensor_temperature_celsius",
+				"netatmo_sensor_temperature_celsius_raw",
+			},
+		},
+		{
+			desc: "wind gauge",
+			device: netatmo.Device{
+				Type: "NAModule2",
+				DashboardData: netatmo.DashboardData{
+					WindStrength: int32Ptr(10),
+					WindAngle:    int32Ptr(90),
+				},
+			},
+			calibration: ModuleCalibration{
+				WindStrength: &Calibration{Factor: 1.2},
+			},
+			wantMetrics: `# HELP netatmo_sensor_wind_direction_degrees Wind direction in degrees
+# TYPE netatmo_sensor_wind_direction_degrees gauge
+netatmo_sensor_wind_direction_degrees{home="Home",module="Test",station="Home (Test)"} 90
+# HELP netatmo_sensor_wind_strength_kph Wind strength in kilometers per hour
+# TYPE netatmo_sensor_wind_strength_kph gauge
+netatmo_sensor_wind_strength_kph{home="Home",module="Test",station="Home (Test)"} 12
+# HELP netatmo_sensor_wind_strength_kph_raw Wind strength in kilometers per hour without calibration
+# TYPE netatmo_sensor_wind_strength_kph_raw gauge
+netatmo_sensor_wind_strength_kph_raw{home="Home",module="Test",station="Home (Test)"} 10
+`,
+			metricNames: []string{
+				"netatmo_sensor_wind_direction_degrees",
+				"netatmo_sensor_wind_direction_degrees_raw",
+				"netatmo_sensor_wind_strength_kph",
+				"netatmo_sensor_wind_strength_kph_raw",
+			},
+		},
+		{
+			desc: "wind gauge v2",
+			device: netatmo.Device{
+				Type: "NAModule2",
+				DashboardData: netatmo.DashboardData{
+					WindStrength: int32Ptr(10),
+				},
+			},
+			calibration: ModuleCalibration{
+				WindStrength: &Calibration{Factor: 1.8},
+			},
+			naming: MetricNamingV2,
+			wantMetrics: `# HELP netatmo_sensor_wind_strength_meters_per_second Wind strength in meters per second
+# TYPE netatmo_sensor_wind_strength_meters_per_second gauge
+netatmo_sensor_wind_strength_meters_per_second{home="Home",module="Test",station="Home (Test)"} 5
+# HELP netatmo_sensor_wind_strength_meters_per_second_raw Wind strength in meters per second without calibration
+# TYPE netatmo_sensor_wind_strength_meters_per_second_raw gauge
+netatmo_sensor_wind_strength_meters_per_second_raw{home="Home",module="Test",station="Home (Test)"} 2.7777777777777777
+`,
+			metricNames: []string{
+				"netatmo_sensor_wind_strength_kph",
+				"netatmo_sensor_wind_strength_kph_raw",
+				"netatmo_sensor_wind_strength_meters_per_second",
+				"netatmo_sensor_wind_strength_meters_per_second_raw",
+			},
+		},
+		{
+			desc: "rain gauge",
+			device: netatmo.Device{
+				Type: "NAModule3",
+				DashboardData: netatmo.DashboardData{
+					Rain: float32Ptr(2),
+				},
+			},
+			calibration: ModuleCalibration{
+				Rain: &Calibration{Factor: 1.5},
+			},
+			wantMetrics: `# HELP netatmo_sensor_rain_amount_mm Rain amount in millimeters
+# TYPE netatmo_sensor_rain_amount_mm gauge
+netatmo_sensor_rain_amount_mm{home="Home",module="Test",station="Home (Test)"} 3
+# HELP netatmo_sensor_rain_amount_mm_raw Rain amount in millimeters without calibration
+# TYPE netatmo_sensor_rain_amount_mm_raw gauge
+netatmo_sensor_rain_amount_mm_raw{home="Home",module="Test",station="Home (Test)"} 2
+`,
+			metricNames: []string{
+				"netatmo_sensor_rain_amount_mm",
+				"netatmo_sensor_rain_amount_mm_raw",
+			},
+		},
+		{
+			desc: "indoor module",
+			device: netatmo.Device{
+				Type: "NAModule4",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(23),
+					Humidity:    int32Ptr(52),
+					CO2:         int32Ptr(510),
+				},
+			},
+			calibration: ModuleCalibration{
+				Temperature: &Calibration{Offset: -0.5},
+				CO2:         &Calibration{Offset: -50},
+			},
+			wantMetrics: `# HELP netatmo_sensor_co2_ppm Carbondioxide measurement in parts per million
+# TYPE netatmo_sensor_co2_ppm gauge
+netatmo_sensor_co2_ppm{home="Home",module="Test",station="Home (Test)"} 460
+# HELP netatmo_sensor_co2_ppm_raw Carbondioxide measurement in parts per million without calibration
+# TYPE netatmo_sensor_co2_ppm_raw gauge
+netatmo_sensor_co2_ppm_raw{home="Home",module="Test",station="Home (Test)"} 510
+# HELP netatmo_sensor_humidity_percent Relative humidity measurement in percent
+# TYPE netatmo_sensor_humidity_percent gauge
+netatmo_sensor_humidity_percent{home="Home",module="Test",station="Home (Test)"} 52
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Test",station="Home (Test)"} 22.5
+# HELP netatmo_sensor_temperature_celsius_raw Temperature measurement in celsius without calibration
+# TYPE netatmo_sensor_temperature_celsius_raw gauge
+netatmo_sensor_temperature_celsius_raw{home="Home",module="Test",station="Home (Test)"} 23
+`,
+			metricNames: []string{
+				"netatmo_sensor_co2_ppm",
+				"netatmo_sensor_co2_ppm_raw",
+				"netatmo_sensor_humidity_percent",
+				"netatmo_sensor_humidity_percent_raw",
+				"netatmo_sensor_temperature_celsius",
+				"netatmo_sensor_temperature_celsius_raw",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			device := &api.Device{
+				Device: tc.device,
+			}
+			device.ID = "aa:bb:cc:dd:ee:f0"
+			device.ModuleName = "Test"
+			device.HomeName = "Home"
+			device.StationName = "Home (Test)"
+			device.DashboardData.LastMeasure = int64Ptr(3500)
+
+			testDevices := &api.DeviceCollection{}
+			testDevices.Body.Devices = []*api.Device{device}
+
+			mockClock := func() time.Time {
+				return time.Unix(3600, 0)
+			}
+			c := New(logrus.New(), func() (*api.DeviceCollection, error) {
+				return testDevices, nil
+			}, time.Hour, time.Hour, Names{})
+			c.clock = mockClock
+			if tc.naming != "" {
+				c.MetricNaming = tc.naming
+			}
+			c.SetCalibration(map[string]ModuleCalibration{
+				"AA:BB:CC:DD:EE:F0": tc.calibration,
+			})
+			c.RefreshData(mockClock())
+
+			if err := testutil.CollectAndCompare(c, strings.NewReader(tc.wantMetrics), tc.metricNames...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -1,38 +1,46 @@
 package collector
 
 import (
+	"context"
+	"errors"
+	"slices"
 	"sync"
 	"time"
 
 	netatmo "github.com/exzz/netatmo-api-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/derived"
+	"github.com/xperimental/netatmo-exporter/v2/internal/signal"
+	"github.com/xperimental/netatmo-exporter/v2/internal/units"
 )
 
 var (
-	prefix        = "netatmo_"
-	netatmoUpDesc = prometheus.NewDesc(prefix+"up",
+	prefix        = DefaultPrefix
+	netatmoUpDesc = newDesc(prefix+"up",
 		"Zero if there was an error during the last refresh try.",
-		nil, nil)
+		nil)
 
-	refreshIntervalDesc = prometheus.NewDesc(
+	refreshIntervalDesc = newDesc(
 		prefix+"refresh_interval_seconds",
 		"Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.",
-		nil, nil)
+		nil)
 	refreshPrefix        = prefix + "last_refresh_"
-	refreshTimestampDesc = prometheus.NewDesc(
+	refreshTimestampDesc = newDesc(
 		refreshPrefix+"time",
 		"Contains the time of the last refresh try, successful or not.",
-		nil, nil)
-	refreshDurationDesc = prometheus.NewDesc(
+		nil)
+	refreshDurationDesc = newDesc(
 		refreshPrefix+"duration_seconds",
 		"Contains the time it took for the last refresh to complete, even if it was unsuccessful.",
-		nil, nil)
+		nil)
 
-	cacheTimestampDesc = prometheus.NewDesc(
+	cacheTimestampDesc = newDesc(
 		prefix+"cache_updated_time",
 		"Contains the time of the cached data.",
-		nil, nil)
+		nil)
 
 	varLabels = []string{
 		"module",
@@ -40,191 +48,574 @@ var (
 		"home",
 	}
 
+	stationLabels = []string{
+		"station",
+		"home",
+	}
+
+	modulePrefix = prefix + "module_"
+
+	reachableDesc = newDesc(
+		modulePrefix+"reachable",
+		"Set to 1 if the module is reachable by the NetAtmo cloud, 0 otherwise.",
+		varLabels)
+
+	lastSeenDesc = newDesc(
+		modulePrefix+"last_seen_time",
+		"Contains the time the module was last seen by its station.",
+		varLabels)
+
+	lastStatusStoreDesc = newDesc(
+		modulePrefix+"last_status_store_time",
+		"Contains the time the last status of the module was stored by the NetAtmo cloud.",
+		varLabels)
+
+	stationLastSetupDesc = newDesc(
+		prefix+"station_last_setup_time",
+		"Contains the time the station was last set up.",
+		stationLabels)
+
 	sensorPrefix = prefix + "sensor_"
 
-	updatedDesc = prometheus.NewDesc(
+	updatedDesc = newDesc(
 		sensorPrefix+"updated",
 		"Timestamp of last update",
-		varLabels,
-		nil)
+		varLabels)
 
-	tempDesc = prometheus.NewDesc(
+	tempDesc = newDesc(
 		sensorPrefix+"temperature_celsius",
 		"Temperature measurement in celsius",
-		varLabels,
-		nil)
+		varLabels)
 
-	humidityDesc = prometheus.NewDesc(
+	humidityDesc = newDesc(
 		sensorPrefix+"humidity_percent",
 		"Relative humidity measurement in percent",
-		varLabels,
-		nil)
+		varLabels)
 
-	cotwoDesc = prometheus.NewDesc(
+	cotwoDesc = newDesc(
 		sensorPrefix+"co2_ppm",
 		"Carbondioxide measurement in parts per million",
-		varLabels,
-		nil)
+		varLabels)
 
-	noiseDesc = prometheus.NewDesc(
+	noiseDesc = newDesc(
 		sensorPrefix+"noise_db",
 		"Noise measurement in decibels",
-		varLabels,
-		nil)
+		varLabels)
 
-	pressureDesc = prometheus.NewDesc(
+	pressureDesc = newDesc(
 		sensorPrefix+"pressure_mb",
 		"Atmospheric pressure measurement in millibar",
-		varLabels,
-		nil)
+		varLabels)
+
+	absolutePressureDesc = newDesc(
+		sensorPrefix+"absolute_pressure_mb",
+		"Atmospheric pressure measurement at the altitude of the station in millibar",
+		varLabels)
 
-	windStrengthDesc = prometheus.NewDesc(
+	windStrengthDesc = newDesc(
 		sensorPrefix+"wind_strength_kph",
 		"Wind strength in kilometers per hour",
-		varLabels,
-		nil)
+		varLabels)
 
-	windDirectionDesc = prometheus.NewDesc(
+	windDirectionDesc = newDesc(
 		sensorPrefix+"wind_direction_degrees",
 		"Wind direction in degrees",
-		varLabels,
-		nil)
+		varLabels)
 
-	rainDesc = prometheus.NewDesc(
+	rainDesc = newDesc(
 		sensorPrefix+"rain_amount_mm",
 		"Rain amount in millimeters",
-		varLabels,
-		nil)
-
-	batteryDesc = prometheus.NewDesc(
+		varLabels)
+
+	tempFahrenheitDesc = newDesc(
+		sensorPrefix+"temperature_fahrenheit",
+		"Temperature measurement in fahrenheit",
+		varLabels)
+
+	pressureInHgDesc = newDesc(
+		sensorPrefix+"pressure_inhg",
+		"Atmospheric pressure measurement in inches of mercury",
+		varLabels)
+
+	absolutePressureInHgDesc = newDesc(
+		sensorPrefix+"absolute_pressure_inhg",
+		"Atmospheric pressure measurement at the altitude of the station in inches of mercury",
+		varLabels)
+
+	windStrengthMphDesc = newDesc(
+		sensorPrefix+"wind_strength_mph",
+		"Wind strength in miles per hour",
+		varLabels)
+
+	rainInchesDesc = newDesc(
+		sensorPrefix+"rain_amount_inches",
+		"Rain amount in inches",
+		varLabels)
+
+	batteryDesc = newDesc(
 		sensorPrefix+"battery_percent",
 		"Battery remaining life (10: low)",
-		varLabels,
-		nil)
-	wifiDesc = prometheus.NewDesc(
+		varLabels)
+	batteryVoltageDesc = newDesc(
+		sensorPrefix+"battery_voltage_volts",
+		"Battery voltage in volts",
+		varLabels)
+	batteryStateDesc = newDesc(
+		sensorPrefix+"battery_state",
+		"Battery state, the series with the current state is set to 1",
+		append(varLabels, "state"))
+	wifiDesc = newDesc(
 		sensorPrefix+"wifi_signal_strength",
 		"Wifi signal strength (86: bad, 71: avg, 56: good)",
-		varLabels,
-		nil)
-	rfDesc = prometheus.NewDesc(
+		varLabels)
+	rfDesc = newDesc(
 		sensorPrefix+"rf_signal_strength",
 		"RF signal strength (90: lowest, 60: highest)",
-		varLabels,
-		nil)
+		varLabels)
+	wifiQualityDesc = newDesc(
+		sensorPrefix+"wifi_quality",
+		"Wifi signal quality derived from the signal strength, the series with the current quality is set to 1",
+		append(varLabels, "quality"))
+	rfQualityDesc = newDesc(
+		sensorPrefix+"rf_quality",
+		"RF signal quality derived from the signal strength, the series with the current quality is set to 1",
+		append(varLabels, "quality"))
+
+	derivedPrefix = prefix + "derived_"
+
+	dewPointDesc = newDesc(
+		derivedPrefix+"dew_point_celsius",
+		"Dew point in celsius, derived from temperature and humidity",
+		varLabels)
+
+	absoluteHumidityDesc = newDesc(
+		derivedPrefix+"absolute_humidity_grams_per_cubic_meter",
+		"Absolute humidity in grams per cubic meter, derived from temperature and humidity",
+		varLabels)
+
+	heatIndexDesc = newDesc(
+		derivedPrefix+"heat_index_celsius",
+		"Heat index in celsius, derived from temperature and humidity",
+		varLabels)
+
+	humidexDesc = newDesc(
+		derivedPrefix+"humidex",
+		"Humidex, derived from temperature and humidity",
+		varLabels)
+
+	qnhDesc = newDesc(
+		derivedPrefix+"pressure_qnh_mb",
+		"Atmospheric pressure reduced to sea level (QNH) in millibar, derived from the absolute pressure and the altitude of the station",
+		varLabels)
+
+	qfeDesc = newDesc(
+		derivedPrefix+"pressure_qfe_mb",
+		"Atmospheric pressure at the altitude of the station (QFE) in millibar, derived from the sea-level pressure and the altitude of the station",
+		varLabels)
+
+	windChillDesc = newDesc(
+		derivedPrefix+"wind_chill_celsius",
+		"Wind chill in celsius, derived from the temperature of the outdoor module and the wind strength of the wind gauge of the same station",
+		varLabels)
+)
+
+const (
+	typeOutdoorModule = "NAModule1"
+	typeWindGauge     = "NAModule2"
+)
+
+const (
+	refreshResultSuccess = "success"
+	refreshResultError   = "error"
+	refreshResultSkipped = "skipped"
 )
 
 // ReadFunction defines the interface for reading from the Netatmo API.
-type ReadFunction func() (*netatmo.DeviceCollection, error)
+type ReadFunction func() (*api.DeviceCollection, error)
+
+// WaitFunction blocks until a request to the Netatmo API is allowed or the context is done.
+type WaitFunction func(ctx context.Context) error
+
+// RefreshFunction is called with the current readings after the data has been refreshed successfully.
+// The readings are shared between all functions and must not be modified.
+type RefreshFunction func(now time.Time, readings []Reading)
 
 // NetatmoCollector is a Prometheus collector for Netatmo sensor values.
 type NetatmoCollector struct {
-	Log             logrus.FieldLogger
-	RefreshInterval time.Duration
-	StaleThreshold  time.Duration
-	ReadFunction    ReadFunction
-	clock           func() time.Time
-
+	Log                   logrus.FieldLogger
+	RefreshInterval       time.Duration
+	StaleThreshold        time.Duration
+	ReadFunction          ReadFunction
+	WaitFunction          WaitFunction
+	WifiThresholds        []int
+	RFThresholds          []int
+	DerivedMetrics        bool
+	UnitSystem            UnitSystem
+	MetricNaming          MetricNaming
+	MeasurementTimestamps bool
+	RefreshHistorySize    int
+	CachePolicy           CachePolicy
+	CacheMaxAge           time.Duration
+	CacheFile             string
+	ModuleFilter          *ModuleFilter
+	PlausibilityCheck     *PlausibilityCheck
+	OnRefresh             []RefreshFunction
+	clock                 func() time.Time
+
+	refreshLock         sync.RWMutex
 	lastRefresh         time.Time
+	refreshInFlight     bool
 	lastRefreshError    error
 	lastRefreshDuration time.Duration
+	refreshHistory      refreshHistory
+	refreshTotal        *prometheus.CounterVec
+	refreshErrors       *prometheus.CounterVec
+	refreshDuration     prometheus.Histogram
 	cacheLock           sync.RWMutex
 	cacheTimestamp      time.Time
-	cachedData          *netatmo.DeviceCollection
+	cachedData          *api.DeviceCollection
+	names               Names
+	extraLabelNames     []string
+	extraLabelValues    map[string][]string
+	calibration         map[string]ModuleCalibration
+	rejected            map[rejection]float64
+	plausibility        map[plausibilityKey]*plausibilityState
+	descs               map[*prometheus.Desc]*prometheus.Desc
 }
 
-func New(log *logrus.Logger, readFunction ReadFunction, refreshInterval, staleDuration time.Duration) *NetatmoCollector {
-	return &NetatmoCollector{
-		Log:             log,
-		RefreshInterval: refreshInterval,
-		StaleThreshold:  staleDuration,
-		ReadFunction:    readFunction,
-		clock:           time.Now,
+// New creates a new collector. The descriptors for the metrics are created using the provided names.
+func New(log *logrus.Logger, readFunction ReadFunction, refreshInterval, staleDuration time.Duration, names Names) *NetatmoCollector {
+	refreshTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: names.metricName(prefix + "refresh_total"),
+		Help: "Number of refresh tries by result.",
+	}, []string{"result"})
+	refreshTotal.WithLabelValues(refreshResultSuccess)
+	refreshTotal.WithLabelValues(refreshResultError)
+	refreshTotal.WithLabelValues(refreshResultSkipped)
+
+	c := &NetatmoCollector{
+		Log:                log,
+		RefreshInterval:    refreshInterval,
+		StaleThreshold:     staleDuration,
+		ReadFunction:       readFunction,
+		WifiThresholds:     signal.DefaultWifiThresholds,
+		RFThresholds:       signal.DefaultRFThresholds,
+		UnitSystem:         UnitSystemMetric,
+		MetricNaming:       MetricNamingV1,
+		RefreshHistorySize: DefaultRefreshHistorySize,
+		CachePolicy:        CachePolicyServeForever,
+		clock:              time.Now,
+		refreshTotal:       refreshTotal,
+		refreshErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: names.metricName(prefix + "refresh_errors_total"),
+			Help: "Number of failed refresh tries by reason.",
+		}, []string{"reason"}),
+		refreshDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    names.metricName(prefix + "refresh_duration_seconds"),
+			Help:    "Duration of refresh tries, successful or not.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		names: names,
 	}
+	c.buildDescs()
+
+	return c
 }
 
 // Describe implements prometheus.Collector
 func (c *NetatmoCollector) Describe(dChan chan<- *prometheus.Desc) {
-	dChan <- netatmoUpDesc
-	dChan <- refreshIntervalDesc
-	dChan <- refreshTimestampDesc
-	dChan <- refreshDurationDesc
-	dChan <- cacheTimestampDesc
-	dChan <- updatedDesc
-	dChan <- tempDesc
-	dChan <- humidityDesc
-	dChan <- cotwoDesc
-	dChan <- noiseDesc
-	dChan <- pressureDesc
-	dChan <- windStrengthDesc
-	dChan <- windDirectionDesc
-	dChan <- rainDesc
-	dChan <- batteryDesc
-	dChan <- wifiDesc
-	dChan <- rfDesc
+	dChan <- c.desc(netatmoUpDesc)
+	dChan <- c.desc(refreshIntervalDesc)
+	dChan <- c.desc(refreshTimestampDesc)
+	dChan <- c.desc(refreshDurationDesc)
+	dChan <- c.desc(cacheTimestampDesc)
+	dChan <- c.desc(cacheAgeDesc)
+	dChan <- c.desc(cacheServingStaleDesc)
+	dChan <- c.desc(modulesFilteredDesc)
+	c.refreshTotal.Describe(dChan)
+	c.refreshErrors.Describe(dChan)
+	c.refreshDuration.Describe(dChan)
+	dChan <- c.desc(moduleInfoDesc)
+	dChan <- c.desc(sensorRejectedDesc)
+	dChan <- c.desc(reachableDesc)
+	dChan <- c.desc(lastSeenDesc)
+	dChan <- c.desc(lastStatusStoreDesc)
+	dChan <- c.desc(stationLastSetupDesc)
+	dChan <- c.desc(updatedDesc)
+	dChan <- c.desc(tempDesc)
+	dChan <- c.desc(humidityDesc)
+	dChan <- c.desc(cotwoDesc)
+	dChan <- c.desc(noiseDesc)
+	dChan <- c.desc(pressureDesc)
+	dChan <- c.desc(absolutePressureDesc)
+	dChan <- c.desc(windStrengthDesc)
+	dChan <- c.desc(windDirectionDesc)
+	dChan <- c.desc(rainDesc)
+	dChan <- c.desc(tempFahrenheitDesc)
+	dChan <- c.desc(pressureInHgDesc)
+	dChan <- c.desc(absolutePressureInHgDesc)
+	dChan <- c.desc(windStrengthMphDesc)
+	dChan <- c.desc(rainInchesDesc)
+	dChan <- c.desc(batteryDesc)
+	dChan <- c.desc(batteryVoltageDesc)
+	dChan <- c.desc(batteryStateDesc)
+	dChan <- c.desc(wifiDesc)
+	dChan <- c.desc(rfDesc)
+	dChan <- c.desc(wifiQualityDesc)
+	dChan <- c.desc(rfQualityDesc)
+	dChan <- c.desc(dewPointDesc)
+	dChan <- c.desc(absoluteHumidityDesc)
+	dChan <- c.desc(heatIndexDesc)
+	dChan <- c.desc(humidexDesc)
+	dChan <- c.desc(qnhDesc)
+	dChan <- c.desc(qfeDesc)
+	dChan <- c.desc(windChillDesc)
+	for _, d := range rawDescs {
+		dChan <- c.desc(d)
+	}
+	for _, m := range v2Metrics {
+		dChan <- c.desc(m.desc)
+	}
 }
 
 // Collect implements prometheus.Collector
 func (c *NetatmoCollector) Collect(mChan chan<- prometheus.Metric) {
 	now := c.clock()
-	if now.Sub(c.lastRefresh) >= c.RefreshInterval {
-		go c.RefreshData(now)
+	if c.claimRefresh(now) {
+		go c.refresh(now)
 	}
 
+	c.refreshLock.RLock()
+	lastRefresh, lastRefreshError, lastRefreshDuration := c.lastRefresh, c.lastRefreshError, c.lastRefreshDuration
+	c.refreshLock.RUnlock()
+
 	upValue := 1.0
-	if c.lastRefresh.IsZero() || c.lastRefreshError != nil {
+	if lastRefresh.IsZero() || lastRefreshError != nil {
 		upValue = 0
 	}
 	c.sendMetric(mChan, netatmoUpDesc, prometheus.GaugeValue, upValue)
 	c.sendMetric(mChan, refreshIntervalDesc, prometheus.GaugeValue, c.RefreshInterval.Seconds())
-	c.sendMetric(mChan, refreshTimestampDesc, prometheus.GaugeValue, convertTime(c.lastRefresh))
-	c.sendMetric(mChan, refreshDurationDesc, prometheus.GaugeValue, c.lastRefreshDuration.Seconds())
+	c.sendMetric(mChan, refreshTimestampDesc, prometheus.GaugeValue, convertTime(lastRefresh))
+	c.sendMetric(mChan, refreshDurationDesc, prometheus.GaugeValue, lastRefreshDuration.Seconds())
+	c.refreshTotal.Collect(mChan)
+	c.refreshErrors.Collect(mChan)
+	c.refreshDuration.Collect(mChan)
+
+	c.cacheLock.Lock()
+	c.expireCache(now)
+	c.cacheLock.Unlock()
 
 	c.cacheLock.RLock()
 	defer c.cacheLock.RUnlock()
 
 	c.sendMetric(mChan, cacheTimestampDesc, prometheus.GaugeValue, convertTime(c.cacheTimestamp))
 	if c.cachedData != nil {
+		c.sendMetric(mChan, cacheAgeDesc, prometheus.GaugeValue, now.Sub(c.cacheTimestamp).Seconds())
+	}
+
+	serve, stale := c.serveCache(now)
+	servingStale := 0.0
+	if serve && stale {
+		servingStale = 1
+	}
+	c.sendMetric(mChan, cacheServingStaleDesc, prometheus.GaugeValue, servingStale)
+	c.collectRejected(mChan)
+
+	filtered := 0
+	if serve {
 		for _, dev := range c.cachedData.Devices() {
 			homeName := dev.HomeName
 			stationName := dev.StationName //nolint: staticcheck
-			c.collectData(mChan, dev, stationName, homeName)
+			if c.ModuleFilter.Match(dev, stationName, homeName) {
+				if dev.LastSetup != nil {
+					c.sendMetric(mChan, stationLastSetupDesc, prometheus.GaugeValue, float64(*dev.LastSetup), stationName, homeName)
+				}
+				c.collectData(mChan, dev, stationName, homeName)
+			} else {
+				filtered++
+			}
 
 			for _, module := range dev.LinkedModules {
+				if !c.ModuleFilter.Match(module, stationName, homeName) {
+					filtered++
+					continue
+				}
+
 				c.collectData(mChan, module, stationName, homeName)
 			}
+
+			if c.DerivedMetrics {
+				c.collectWindChill(mChan, dev, stationName, homeName)
+			}
 		}
 	}
+	c.sendMetric(mChan, modulesFilteredDesc, prometheus.GaugeValue, float64(filtered))
 }
 
 // RefreshData causes the collector to try to refresh the cached data.
 func (c *NetatmoCollector) RefreshData(now time.Time) {
+	c.refreshLock.Lock()
+	c.startRefresh(now)
+	c.refreshLock.Unlock()
+
+	c.refresh(now)
+}
+
+// claimRefresh starts a refresh, if one is due and no other refresh is in flight.
+// The caller needs to call refresh, if it returns true.
+func (c *NetatmoCollector) claimRefresh(now time.Time) bool {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	if c.refreshInFlight || c.RefreshInterval-now.Sub(c.lastRefresh) > 0 {
+		return false
+	}
+
+	c.startRefresh(now)
+	return true
+}
+
+// startRefresh marks a refresh as in flight. The caller needs to hold the refreshLock.
+func (c *NetatmoCollector) startRefresh(now time.Time) {
 	c.Log.Debugf("Refreshing data. Time since last refresh: %s", now.Sub(c.lastRefresh))
 	c.lastRefresh = now
+	c.refreshInFlight = true
+}
+
+// refresh reads the data and updates the cache. The refresh needs to be started using startRefresh before.
+func (c *NetatmoCollector) refresh(now time.Time) {
+	defer func() {
+		c.refreshLock.Lock()
+		c.refreshInFlight = false
+		c.refreshLock.Unlock()
+	}()
 
+	attempt := RefreshAttempt{
+		Start: now,
+	}
 	defer func(start time.Time) {
-		c.lastRefreshDuration = c.clock().Sub(start)
+		attempt.Duration = c.clock().Sub(start)
+		c.refreshLock.Lock()
+		c.lastRefreshDuration = attempt.Duration
+		c.refreshLock.Unlock()
+		c.refreshHistory.add(c.RefreshHistorySize, attempt)
+
+		switch {
+		case errors.Is(attempt.Error, api.ErrBudgetExhausted):
+			// Skipping is deliberate, so it is neither an error nor a try to be timed.
+			c.refreshTotal.WithLabelValues(refreshResultSkipped).Inc()
+		case attempt.Success():
+			c.refreshDuration.Observe(attempt.Duration.Seconds())
+			c.refreshTotal.WithLabelValues(refreshResultSuccess).Inc()
+		default:
+			c.refreshDuration.Observe(attempt.Duration.Seconds())
+			c.refreshTotal.WithLabelValues(refreshResultError).Inc()
+			c.refreshErrors.WithLabelValues(attempt.ErrorClass).Inc()
+		}
 	}(c.clock())
 
 	devices, err := c.ReadFunction()
+	attempt.Error = err
+	attempt.ErrorClass = errorClass(err)
+	if errors.Is(err, api.ErrBudgetExhausted) {
+		c.Log.Warn("Skipping refresh, because the API request budget is exhausted.")
+		return
+	}
+
+	c.refreshLock.Lock()
 	c.lastRefreshError = err
+	c.refreshLock.Unlock()
 	if err != nil {
 		c.Log.Errorf("Error during refresh: %s", err)
 		return
 	}
 
+	for _, dev := range devices.Devices() {
+		attempt.Devices++
+		attempt.Modules += len(dev.LinkedModules)
+	}
+
 	c.cacheLock.Lock()
-	defer c.cacheLock.Unlock()
+	rejections := c.checkPlausibility(devices, c.cachedData)
 	c.cacheTimestamp = now
 	c.cachedData = devices
+	if len(rejections) > 0 && c.rejected == nil {
+		c.rejected = make(map[rejection]float64)
+	}
+	for _, r := range rejections {
+		c.rejected[r]++
+	}
+	c.cacheLock.Unlock()
+
+	if err := c.saveCache(now, devices); err != nil {
+		c.Log.Errorf("Error saving cache: %s", err)
+	}
+
+	if len(c.OnRefresh) > 0 {
+		readings := c.readings(devices)
+		for _, fn := range c.OnRefresh {
+			fn(now, readings)
+		}
+	}
+}
+
+// refreshDue returns true, if the last refresh was started at least one refresh interval ago.
+func (c *NetatmoCollector) refreshDue(now time.Time) bool {
+	return c.refreshDelay(now) <= 0
 }
 
-func (c *NetatmoCollector) collectData(ch chan<- prometheus.Metric, device *netatmo.Device, stationName, homeName string) {
-	moduleName := device.ModuleName
-	if moduleName == "" {
-		moduleName = "id-" + device.ID
+// refreshDelay returns the time until the next refresh is due.
+func (c *NetatmoCollector) refreshDelay(now time.Time) time.Duration {
+	c.refreshLock.RLock()
+	defer c.refreshLock.RUnlock()
+
+	return c.RefreshInterval - now.Sub(c.lastRefresh)
+}
+
+// RefreshLoop refreshes the data in the refresh interval until the context is canceled.
+// It is needed when the readings are pushed, because then the refresh can not rely on scrapes.
+// The next refresh is scheduled relative to the last one, so refreshes started by scrapes do not delay it.
+// Unlike refreshes started by scrapes, it waits for the API budget using the WaitFunction instead of skipping the refresh.
+func (c *NetatmoCollector) RefreshLoop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if c.refreshDue(c.clock()) {
+			if c.WaitFunction != nil {
+				if err := c.WaitFunction(ctx); err != nil {
+					return
+				}
+			}
+
+			// A scrape might have refreshed the data while waiting.
+			if now := c.clock(); c.claimRefresh(now) {
+				c.refresh(now)
+			}
+		}
+
+		timer.Reset(max(c.refreshDelay(c.clock()), 0))
 	}
+}
+
+// RefreshHistory returns the latest refresh attempts, newest first.
+func (c *NetatmoCollector) RefreshHistory() []RefreshAttempt {
+	return c.refreshHistory.list()
+}
 
-	data := device.DashboardData
+func (c *NetatmoCollector) collectData(ch chan<- prometheus.Metric, device *api.Device, stationName, homeName string) {
+	moduleName := moduleName(device)
+	labels := c.moduleLabels(device.ID, moduleName, stationName, homeName)
+	c.sendMetric(ch, moduleInfoDesc, prometheus.GaugeValue, 1, append(slices.Clone(labels), device.ID, device.Type)...)
+	c.collectConnectivity(ch, device, labels...)
+
+	data := c.calibratedData(device)
 
 	if data.LastMeasure == nil {
 		c.Log.Debugf("No data available.")
@@ -238,57 +629,251 @@ func (c *NetatmoCollector) collectData(ch chan<- prometheus.Metric, device *neta
 		return
 	}
 
-	c.sendMetric(ch, updatedDesc, prometheus.GaugeValue, float64(date.UTC().Unix()), moduleName, stationName, homeName)
+	timestamp := time.Time{}
+	if c.MeasurementTimestamps {
+		timestamp = date
+	}
+
+	c.sendMetricAt(ch, updatedDesc, prometheus.GaugeValue, float64(date.UTC().Unix()), timestamp, labels...)
 
 	if data.Temperature != nil {
-		c.sendMetric(ch, tempDesc, prometheus.GaugeValue, float64(*data.Temperature), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, tempDesc, prometheus.GaugeValue, float64(*data.Temperature), timestamp, labels...)
 	}
 
 	if data.Humidity != nil {
-		c.sendMetric(ch, humidityDesc, prometheus.GaugeValue, float64(*data.Humidity), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, humidityDesc, prometheus.GaugeValue, float64(*data.Humidity), timestamp, labels...)
 	}
 
 	if data.CO2 != nil {
-		c.sendMetric(ch, cotwoDesc, prometheus.GaugeValue, float64(*data.CO2), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, cotwoDesc, prometheus.GaugeValue, float64(*data.CO2), timestamp, labels...)
 	}
 
 	if data.Noise != nil {
-		c.sendMetric(ch, noiseDesc, prometheus.GaugeValue, float64(*data.Noise), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, noiseDesc, prometheus.GaugeValue, float64(*data.Noise), timestamp, labels...)
 	}
 
 	if data.Pressure != nil {
-		c.sendMetric(ch, pressureDesc, prometheus.GaugeValue, float64(*data.Pressure), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, pressureDesc, prometheus.GaugeValue, float64(*data.Pressure), timestamp, labels...)
+	}
+
+	if data.AbsolutePressure != nil {
+		c.sendMetricAt(ch, absolutePressureDesc, prometheus.GaugeValue, float64(*data.AbsolutePressure), timestamp, labels...)
 	}
 
 	if data.WindStrength != nil {
-		c.sendMetric(ch, windStrengthDesc, prometheus.GaugeValue, float64(*data.WindStrength), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, windStrengthDesc, prometheus.GaugeValue, float64(*data.WindStrength), timestamp, labels...)
 	}
 
 	if data.WindAngle != nil {
-		c.sendMetric(ch, windDirectionDesc, prometheus.GaugeValue, float64(*data.WindAngle), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, windDirectionDesc, prometheus.GaugeValue, float64(*data.WindAngle), timestamp, labels...)
 	}
 
 	if data.Rain != nil {
-		c.sendMetric(ch, rainDesc, prometheus.GaugeValue, float64(*data.Rain), moduleName, stationName, homeName)
+		c.sendMetricAt(ch, rainDesc, prometheus.GaugeValue, float64(*data.Rain), timestamp, labels...)
+	}
+
+	c.collectRaw(ch, device, timestamp, labels...)
+
+	if c.UnitSystem == UnitSystemImperial {
+		c.collectImperial(ch, data, timestamp, labels...)
+	}
+
+	// A failed humidity sensor reports zero, which would result in NaN values.
+	if c.DerivedMetrics && data.Temperature != nil && data.Humidity != nil && *data.Humidity > 0 {
+		temperature := float64(*data.Temperature)
+		humidity := float64(*data.Humidity)
+		dewPoint := derived.DewPoint(temperature, humidity)
+
+		c.sendMetricAt(ch, dewPointDesc, prometheus.GaugeValue, dewPoint, timestamp, labels...)
+		c.sendMetricAt(ch, absoluteHumidityDesc, prometheus.GaugeValue, derived.AbsoluteHumidity(temperature, humidity), timestamp, labels...)
+		c.sendMetricAt(ch, heatIndexDesc, prometheus.GaugeValue, derived.HeatIndex(temperature, humidity), timestamp, labels...)
+		c.sendMetricAt(ch, humidexDesc, prometheus.GaugeValue, derived.Humidex(temperature, dewPoint), timestamp, labels...)
+	}
+
+	if c.DerivedMetrics && device.Place != nil && device.Place.Altitude != nil {
+		altitude := *device.Place.Altitude
+
+		if data.AbsolutePressure != nil {
+			c.sendMetricAt(ch, qnhDesc, prometheus.GaugeValue, derived.QNH(float64(*data.AbsolutePressure), altitude), timestamp, labels...)
+		}
+
+		if data.Pressure != nil {
+			c.sendMetricAt(ch, qfeDesc, prometheus.GaugeValue, derived.QFE(float64(*data.Pressure), altitude), timestamp, labels...)
+		}
 	}
 
 	if device.BatteryPercent != nil {
-		c.sendMetric(ch, batteryDesc, prometheus.GaugeValue, float64(*device.BatteryPercent), moduleName, stationName, homeName)
+		c.sendMetric(ch, batteryDesc, prometheus.GaugeValue, float64(*device.BatteryPercent), labels...)
+	}
+	if device.BatteryVP != nil {
+		c.sendMetric(ch, batteryVoltageDesc, prometheus.GaugeValue, float64(*device.BatteryVP)/1000, labels...)
+	}
+	if state := batteryState(device); state != "" {
+		c.collectEnum(ch, batteryStateDesc, batteryStates, state, labels...)
 	}
 	if device.WifiStatus != nil {
-		c.sendMetric(ch, wifiDesc, prometheus.GaugeValue, float64(*device.WifiStatus), moduleName, stationName, homeName)
+		c.sendMetric(ch, wifiDesc, prometheus.GaugeValue, float64(*device.WifiStatus), labels...)
+
+		quality := signal.Quality(*device.WifiStatus, c.WifiThresholds, signal.WifiQualities)
+		c.collectEnum(ch, wifiQualityDesc, signal.WifiQualities, quality, labels...)
 	}
 	if device.RFStatus != nil {
-		c.sendMetric(ch, rfDesc, prometheus.GaugeValue, float64(*device.RFStatus), moduleName, stationName, homeName)
+		c.sendMetric(ch, rfDesc, prometheus.GaugeValue, float64(*device.RFStatus), labels...)
+
+		quality := signal.Quality(*device.RFStatus, c.RFThresholds, signal.RFQualities)
+		c.collectEnum(ch, rfQualityDesc, signal.RFQualities, quality, labels...)
+	}
+}
+
+// collectImperial sends the sensor values, which have a metric unit, converted to imperial units.
+func (c *NetatmoCollector) collectImperial(ch chan<- prometheus.Metric, data netatmo.DashboardData, timestamp time.Time, labels ...string) {
+	if data.Temperature != nil {
+		c.sendMetricAt(ch, tempFahrenheitDesc, prometheus.GaugeValue, units.CelsiusToFahrenheit(float64(*data.Temperature)), timestamp, labels...)
+	}
+
+	if data.Pressure != nil {
+		c.sendMetricAt(ch, pressureInHgDesc, prometheus.GaugeValue, units.MillibarToInchesOfMercury(float64(*data.Pressure)), timestamp, labels...)
+	}
+
+	if data.AbsolutePressure != nil {
+		c.sendMetricAt(ch, absolutePressureInHgDesc, prometheus.GaugeValue, units.MillibarToInchesOfMercury(float64(*data.AbsolutePressure)), timestamp, labels...)
+	}
+
+	if data.WindStrength != nil {
+		c.sendMetricAt(ch, windStrengthMphDesc, prometheus.GaugeValue, units.KilometersPerHourToMilesPerHour(float64(*data.WindStrength)), timestamp, labels...)
+	}
+
+	if data.Rain != nil {
+		c.sendMetricAt(ch, rainInchesDesc, prometheus.GaugeValue, units.MillimetersToInches(float64(*data.Rain)), timestamp, labels...)
+	}
+}
+
+// collectWindChill combines the temperature of the outdoor module with the wind strength of the wind gauge of the same station.
+// The metric uses the labels of the outdoor module.
+func (c *NetatmoCollector) collectWindChill(ch chan<- prometheus.Metric, station *api.Device, stationName, homeName string) {
+	var outdoor, wind *api.Device
+	for _, module := range station.LinkedModules {
+		if !c.hasCurrentData(module) || !c.ModuleFilter.Match(module, stationName, homeName) {
+			continue
+		}
+
+		switch {
+		case module.Type == typeOutdoorModule && module.DashboardData.Temperature != nil:
+			outdoor = module
+		case module.Type == typeWindGauge && module.DashboardData.WindStrength != nil:
+			wind = module
+		}
+	}
+
+	if outdoor == nil || wind == nil {
+		return
+	}
+
+	timestamp := time.Time{}
+	if c.MeasurementTimestamps {
+		timestamp = time.Unix(*outdoor.DashboardData.LastMeasure, 0)
+	}
+
+	windChill := derived.WindChill(float64(*c.calibratedData(outdoor).Temperature), float64(*c.calibratedData(wind).WindStrength))
+	c.sendMetricAt(ch, windChillDesc, prometheus.GaugeValue, windChill, timestamp, c.moduleLabels(outdoor.ID, moduleName(outdoor), stationName, homeName)...)
+}
+
+// moduleName returns the name of the module used in the labels. Modules without a name use their ID instead.
+func moduleName(device *api.Device) string {
+	if device.ModuleName == "" {
+		return "id-" + device.ID
 	}
+
+	return device.ModuleName
+}
+
+// hasCurrentData returns true if the device has data, which is not stale.
+func (c *NetatmoCollector) hasCurrentData(device *api.Device) bool {
+	if device.DashboardData.LastMeasure == nil {
+		return false
+	}
+
+	date := time.Unix(*device.DashboardData.LastMeasure, 0)
+	return c.clock().Sub(date) <= c.StaleThreshold
 }
 
+// collectConnectivity sends the metrics about the connection state of a module.
+// These are sent independently of the sensor data, because they are most useful when there is no current data.
+func (c *NetatmoCollector) collectConnectivity(ch chan<- prometheus.Metric, device *api.Device, labels ...string) {
+	if device.Reachable != nil {
+		reachableValue := 0.0
+		if *device.Reachable {
+			reachableValue = 1.0
+		}
+		c.sendMetric(ch, reachableDesc, prometheus.GaugeValue, reachableValue, labels...)
+	}
+
+	if device.LastSeen != nil {
+		c.sendMetric(ch, lastSeenDesc, prometheus.GaugeValue, float64(*device.LastSeen), labels...)
+	}
+
+	// Stations report "last_status_store", while modules report the equivalent "last_message".
+	lastStatusStore := device.LastStatusStore
+	if lastStatusStore == nil {
+		lastStatusStore = device.LastMessage
+	}
+	if lastStatusStore != nil {
+		c.sendMetric(ch, lastStatusStoreDesc, prometheus.GaugeValue, float64(*lastStatusStore), labels...)
+	}
+}
+
+// collectEnum sends one series per known value, setting the series of the current value to 1.
+// If the current value is not one of the known values, an additional series is sent for it.
+func (c *NetatmoCollector) collectEnum(ch chan<- prometheus.Metric, desc *prometheus.Desc, values []string, current string, labels ...string) {
+	known := false
+	for _, v := range values {
+		value := 0.0
+		if v == current {
+			value = 1.0
+			known = true
+		}
+		c.sendMetric(ch, desc, prometheus.GaugeValue, value, append(slices.Clone(labels), v)...)
+	}
+
+	if !known {
+		c.sendMetric(ch, desc, prometheus.GaugeValue, 1.0, append(slices.Clone(labels), current)...)
+	}
+}
+
+// sendMetric sends a metric without an explicit timestamp using the configured naming scheme.
 func (c *NetatmoCollector) sendMetric(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labelValues ...string) {
+	c.sendMetricAt(ch, desc, valueType, value, time.Time{}, labelValues...)
+}
+
+// sendMetricAt sends a metric using the configured naming scheme.
+// If the timestamp is not zero, it is attached to the metric as the sample timestamp.
+func (c *NetatmoCollector) sendMetricAt(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, value float64, timestamp time.Time, labelValues ...string) {
+	v2, ok := v2Metrics[desc]
+	if !ok {
+		c.sendConstMetric(ch, desc, valueType, value, timestamp, labelValues...)
+		return
+	}
+
+	if c.MetricNaming != MetricNamingV2 {
+		c.sendConstMetric(ch, desc, valueType, value, timestamp, labelValues...)
+	}
+
+	if c.MetricNaming != MetricNamingV1 {
+		c.sendConstMetric(ch, v2.desc, valueType, value*v2.factor, timestamp, labelValues...)
+	}
+}
+
+func (c *NetatmoCollector) sendConstMetric(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, value float64, timestamp time.Time, labelValues ...string) {
+	desc = c.desc(desc)
 	m, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
 	if err != nil {
-		c.Log.Errorf("Error creating %s metric: %s", updatedDesc.String(), err)
+		c.Log.Errorf("Error creating %s metric: %s", desc.String(), err)
 		return
 	}
+
+	if !timestamp.IsZero() {
+		m = prometheus.NewMetricWithTimestamp(timestamp, m)
+	}
 	ch <- m
 }
 
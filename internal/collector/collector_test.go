@@ -10,23 +10,25 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
 )
 
 func TestRefreshData(t *testing.T) {
-	testData := &netatmo.DeviceCollection{}
+	testData := &api.DeviceCollection{}
 	testError := errors.New("test error")
 	tt := []struct {
 		desc         string
 		time         time.Time
 		readFunction ReadFunction
 		wantTime     time.Time
-		wantData     *netatmo.DeviceCollection
+		wantData     *api.DeviceCollection
 		wantError    error
 	}{
 		{
 			desc: "success",
 			time: time.Unix(0, 0),
-			readFunction: func() (*netatmo.DeviceCollection, error) {
+			readFunction: func() (*api.DeviceCollection, error) {
 				return testData, nil
 			},
 			wantTime:  time.Unix(0, 0),
@@ -36,13 +38,23 @@ func TestRefreshData(t *testing.T) {
 		{
 			desc: "error",
 			time: time.Unix(0, 0),
-			readFunction: func() (*netatmo.DeviceCollection, error) {
+			readFunction: func() (*api.DeviceCollection, error) {
 				return nil, testError
 			},
 			wantTime:  time.Time{},
 			wantData:  nil,
 			wantError: testError,
 		},
+		{
+			desc: "budget exhausted",
+			time: time.Unix(0, 0),
+			readFunction: func() (*api.DeviceCollection, error) {
+				return nil, api.ErrBudgetExhausted
+			},
+			wantTime:  time.Time{},
+			wantData:  nil,
+			wantError: nil,
+		},
 	}
 
 	for _, tc := range tt {
@@ -50,7 +62,7 @@ func TestRefreshData(t *testing.T) {
 		t.Run(tc.desc, func(t *testing.T) {
 			t.Parallel()
 
-			c := New(logrus.New(), tc.readFunction, 0, 0)
+			c := New(logrus.New(), tc.readFunction, 0, 0, Names{})
 			c.RefreshData(tc.time)
 
 			if c.cacheTimestamp != tc.wantTime {
@@ -69,16 +81,16 @@ func TestRefreshData(t *testing.T) {
 }
 
 func TestRefreshDataResetError(t *testing.T) {
-	testData := &netatmo.DeviceCollection{}
+	testData := &api.DeviceCollection{}
 	testError := errors.New("test error")
-	successFunc := func() (*netatmo.DeviceCollection, error) {
+	successFunc := func() (*api.DeviceCollection, error) {
 		return testData, nil
 	}
-	errorFunc := func() (*netatmo.DeviceCollection, error) {
+	errorFunc := func() (*api.DeviceCollection, error) {
 		return nil, testError
 	}
 
-	c := New(logrus.New(), successFunc, 0, 0)
+	c := New(logrus.New(), successFunc, 0, 0, Names{})
 	c.RefreshData(time.Unix(0, 0))
 
 	if c.lastRefreshError != nil {
@@ -100,63 +112,181 @@ func TestRefreshDataResetError(t *testing.T) {
 	}
 }
 
+func TestRefreshDataHistory(t *testing.T) {
+	testData := &api.DeviceCollection{}
+	testData.Body.Devices = []*api.Device{
+		{
+			LinkedModules: []*api.Device{{}, {}},
+		},
+		{},
+	}
+	testError := errors.New("test error")
+
+	c := New(logrus.New(), func() (*api.DeviceCollection, error) {
+		return testData, nil
+	}, 0, 0, Names{})
+	c.RefreshHistorySize = 2
+	c.RefreshData(time.Unix(0, 0))
+
+	c.ReadFunction = func() (*api.DeviceCollection, error) {
+		return nil, testError
+	}
+	c.RefreshData(time.Unix(1, 0))
+
+	c.ReadFunction = func() (*api.DeviceCollection, error) {
+		return nil, api.ErrBudgetExhausted
+	}
+	c.RefreshData(time.Unix(2, 0))
+
+	history := c.RefreshHistory()
+	if len(history) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(history))
+	}
+
+	if history[0].Start != time.Unix(2, 0) || history[0].ErrorClass != errorClassBudgetExhausted {
+		t.Errorf("got newest attempt %+v", history[0])
+	}
+
+	if history[1].Start != time.Unix(1, 0) || history[1].Error != testError || history[1].ErrorClass != errorClassUnknown {
+		t.Errorf("got oldest attempt %+v", history[1])
+	}
+
+	c.RefreshHistorySize = 1
+	c.ReadFunction = func() (*api.DeviceCollection, error) {
+		return testData, nil
+	}
+	c.RefreshData(time.Unix(3, 0))
+
+	history = c.RefreshHistory()
+	if len(history) != 1 || !history[0].Success() || history[0].Devices != 2 || history[0].Modules != 2 {
+		t.Errorf("got history %+v", history)
+	}
+}
+
+func TestRefreshMetrics(t *testing.T) {
+	results := []error{
+		nil,
+		errors.New("test error"),
+		&api.Error{StatusCode: 500},
+		nil,
+		api.ErrBudgetExhausted,
+	}
+
+	c := New(logrus.New(), nil, time.Hour, time.Hour, Names{})
+	c.clock = func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	for _, err := range results {
+		err := err
+		c.ReadFunction = func() (*api.DeviceCollection, error) {
+			return &api.DeviceCollection{}, err
+		}
+		c.RefreshData(c.clock())
+	}
+
+	wantMetrics := `# HELP netatmo_refresh_errors_total Number of failed refresh tries by reason.
+# TYPE netatmo_refresh_errors_total counter
+netatmo_refresh_errors_total{reason="api"} 1
+netatmo_refresh_errors_total{reason="unknown"} 1
+# HELP netatmo_refresh_total Number of refresh tries by result.
+# TYPE netatmo_refresh_total counter
+netatmo_refresh_total{result="error"} 2
+netatmo_refresh_total{result="skipped"} 1
+netatmo_refresh_total{result="success"} 2
+`
+	metricNames := []string{
+		"netatmo_refresh_errors_total",
+		"netatmo_refresh_total",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestNetatmoCollector_Collect(t *testing.T) {
-	testDevices := &netatmo.DeviceCollection{}
-	testDevices.Body.Devices = []*netatmo.Device{
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
 		{
-			ID:          "aa:bb:cc:dd:ee:f0",
-			ModuleName:  "Living Room",
-			HomeID:      "0123456789abcdef01234567",
-			HomeName:    "Home",
-			StationName: "Home (Living Room)",
-			WifiStatus:  int32Ptr(45),
-			Type:        "NAMain",
-			DashboardData: netatmo.DashboardData{
-				Temperature:      float32Ptr(23),
-				Humidity:         int32Ptr(45),
-				CO2:              int32Ptr(650),
-				Noise:            int32Ptr(40),
-				Pressure:         float32Ptr(1234),
-				AbsolutePressure: float32Ptr(987),
-				LastMeasure:      int64Ptr(3500),
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeID:      "0123456789abcdef01234567",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				WifiStatus:  int32Ptr(45),
+				Type:        "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature:      float32Ptr(23),
+					Humidity:         int32Ptr(45),
+					CO2:              int32Ptr(650),
+					Noise:            int32Ptr(40),
+					Pressure:         float32Ptr(1234),
+					AbsolutePressure: float32Ptr(987),
+					LastMeasure:      int64Ptr(3500),
+				},
 			},
-			LinkedModules: []*netatmo.Device{
+			Reachable:       boolPtr(true),
+			LastSetup:       int64Ptr(1000),
+			LastStatusStore: int64Ptr(3510),
+			LinkedModules: []*api.Device{
+				{
+					Device: netatmo.Device{
+						ID:             "aa:bb:cc:dd:ee:f1",
+						ModuleName:     "Outside",
+						BatteryPercent: int32Ptr(70),
+						RFStatus:       int32Ptr(57),
+						Type:           "NAModule1",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(5),
+							Humidity:    int32Ptr(83),
+							LastMeasure: int64Ptr(3501),
+						},
+					},
+					Reachable:   boolPtr(true),
+					LastSeen:    int64Ptr(3505),
+					LastMessage: int64Ptr(3506),
+					BatteryVP:   int32Ptr(5200),
+				},
 				{
-					ID:             "aa:bb:cc:dd:ee:f1",
-					ModuleName:     "Outside",
-					BatteryPercent: int32Ptr(70),
-					RFStatus:       int32Ptr(57),
-					Type:           "NAModule1",
-					DashboardData: netatmo.DashboardData{
-						Temperature: float32Ptr(5),
-						Humidity:    int32Ptr(83),
-						LastMeasure: int64Ptr(3501),
+					Device: netatmo.Device{
+						ID:             "aa:bb:cc:dd:ee:f2",
+						ModuleName:     "Bedroom",
+						BatteryPercent: int32Ptr(55),
+						RFStatus:       int32Ptr(80),
+						Type:           "NAModule4",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(17),
+							Humidity:    int32Ptr(52),
+							CO2:         int32Ptr(510),
+							LastMeasure: int64Ptr(3502),
+						},
 					},
+					BatteryVP:    int32Ptr(4700),
+					BatteryState: stringPtr("low"),
 				},
 				{
-					ID:             "aa:bb:cc:dd:ee:f2",
-					ModuleName:     "Bedroom",
-					BatteryPercent: int32Ptr(55),
-					RFStatus:       int32Ptr(80),
-					Type:           "NAModule4",
-					DashboardData: netatmo.DashboardData{
-						Temperature: float32Ptr(17),
-						Humidity:    int32Ptr(52),
-						CO2:         int32Ptr(510),
-						LastMeasure: int64Ptr(3502),
+					Device: netatmo.Device{
+						ID:             "aa:bb:cc:dd:ee:f3",
+						BatteryPercent: int32Ptr(60),
+						RFStatus:       int32Ptr(70),
+						Type:           "NAModule4",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(23),
+							Humidity:    int32Ptr(75),
+							CO2:         int32Ptr(750),
+							LastMeasure: int64Ptr(3503),
+						},
 					},
 				},
 				{
-					ID:             "aa:bb:cc:dd:ee:f3",
-					BatteryPercent: int32Ptr(60),
-					RFStatus:       int32Ptr(70),
-					Type:           "NAModule4",
-					DashboardData: netatmo.DashboardData{
-						Temperature: float32Ptr(23),
-						Humidity:    int32Ptr(75),
-						CO2:         int32Ptr(750),
-						LastMeasure: int64Ptr(3503),
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f4",
+						ModuleName: "Garden",
+						Type:       "NAModule2",
 					},
+					Reachable:   boolPtr(false),
+					LastSeen:    int64Ptr(100),
+					LastMessage: int64Ptr(110),
 				},
 			},
 		},
@@ -164,24 +294,54 @@ func TestNetatmoCollector_Collect(t *testing.T) {
 
 	tt := []struct {
 		desc        string
-		data        *netatmo.DeviceCollection
+		data        *api.DeviceCollection
 		wantMetrics string
 	}{
 		{
 			desc: "success, no data",
-			data: &netatmo.DeviceCollection{},
-			wantMetrics: `# HELP netatmo_cache_updated_time Contains the time of the cached data.
+			data: &api.DeviceCollection{},
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+		# TYPE netatmo_cache_age_seconds gauge
+		netatmo_cache_age_seconds 0
+		# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+		# TYPE netatmo_cache_serving_stale gauge
+		netatmo_cache_serving_stale 0
+		# HELP netatmo_cache_updated_time Contains the time of the cached data.
 		# TYPE netatmo_cache_updated_time gauge
 		netatmo_cache_updated_time 3600
+		# HELP netatmo_modules_filtered Number of modules hidden by the module filters.
+		# TYPE netatmo_modules_filtered gauge
+		netatmo_modules_filtered 0
 		# HELP netatmo_last_refresh_duration_seconds Contains the time it took for the last refresh to complete, even if it was unsuccessful.
 		# TYPE netatmo_last_refresh_duration_seconds gauge
 		netatmo_last_refresh_duration_seconds 0
 		# HELP netatmo_last_refresh_time Contains the time of the last refresh try, successful or not.
 		# TYPE netatmo_last_refresh_time gauge
 		netatmo_last_refresh_time 3600
+		# HELP netatmo_refresh_duration_seconds Duration of refresh tries, successful or not.
+		# TYPE netatmo_refresh_duration_seconds histogram
+		netatmo_refresh_duration_seconds_bucket{le="0.005"} 1
+		netatmo_refresh_duration_seconds_bucket{le="0.01"} 1
+		netatmo_refresh_duration_seconds_bucket{le="0.025"} 1
+		netatmo_refresh_duration_seconds_bucket{le="0.05"} 1
+		netatmo_refresh_duration_seconds_bucket{le="0.1"} 1
+		netatmo_refresh_duration_seconds_bucket{le="0.25"} 1
+		netatmo_refresh_duration_seconds_bucket{le="0.5"} 1
+		netatmo_refresh_duration_seconds_bucket{le="1"} 1
+		netatmo_refresh_duration_seconds_bucket{le="2.5"} 1
+		netatmo_refresh_duration_seconds_bucket{le="5"} 1
+		netatmo_refresh_duration_seconds_bucket{le="10"} 1
+		netatmo_refresh_duration_seconds_bucket{le="+Inf"} 1
+		netatmo_refresh_duration_seconds_sum 0
+		netatmo_refresh_duration_seconds_count 1
 		# HELP netatmo_refresh_interval_seconds Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.
 		# TYPE netatmo_refresh_interval_seconds gauge
 		netatmo_refresh_interval_seconds 3600
+		# HELP netatmo_refresh_total Number of refresh tries by result.
+		# TYPE netatmo_refresh_total counter
+		netatmo_refresh_total{result="error"} 0
+		netatmo_refresh_total{result="skipped"} 0
+		netatmo_refresh_total{result="success"} 1
 		# HELP netatmo_up Zero if there was an error during the last refresh try.
 		# TYPE netatmo_up gauge
 		netatmo_up 1
@@ -190,23 +350,90 @@ func TestNetatmoCollector_Collect(t *testing.T) {
 		{
 			desc: "success",
 			data: testDevices,
-			wantMetrics: `# HELP netatmo_cache_updated_time Contains the time of the cached data.
+			wantMetrics: `# HELP netatmo_cache_age_seconds Age of the cached data in seconds. Only present while there is cached data.
+# TYPE netatmo_cache_age_seconds gauge
+netatmo_cache_age_seconds 0
+# HELP netatmo_cache_serving_stale One if the cached data is served although the latest refresh was not successful.
+# TYPE netatmo_cache_serving_stale gauge
+netatmo_cache_serving_stale 0
+# HELP netatmo_cache_updated_time Contains the time of the cached data.
 # TYPE netatmo_cache_updated_time gauge
 netatmo_cache_updated_time 3600
+# HELP netatmo_modules_filtered Number of modules hidden by the module filters.
+# TYPE netatmo_modules_filtered gauge
+netatmo_modules_filtered 0
 # HELP netatmo_last_refresh_duration_seconds Contains the time it took for the last refresh to complete, even if it was unsuccessful.
 # TYPE netatmo_last_refresh_duration_seconds gauge
 netatmo_last_refresh_duration_seconds 0
 # HELP netatmo_last_refresh_time Contains the time of the last refresh try, successful or not.
 # TYPE netatmo_last_refresh_time gauge
 netatmo_last_refresh_time 3600
+# HELP netatmo_refresh_duration_seconds Duration of refresh tries, successful or not.
+# TYPE netatmo_refresh_duration_seconds histogram
+netatmo_refresh_duration_seconds_bucket{le="0.005"} 1
+netatmo_refresh_duration_seconds_bucket{le="0.01"} 1
+netatmo_refresh_duration_seconds_bucket{le="0.025"} 1
+netatmo_refresh_duration_seconds_bucket{le="0.05"} 1
+netatmo_refresh_duration_seconds_bucket{le="0.1"} 1
+netatmo_refresh_duration_seconds_bucket{le="0.25"} 1
+netatmo_refresh_duration_seconds_bucket{le="0.5"} 1
+netatmo_refresh_duration_seconds_bucket{le="1"} 1
+netatmo_refresh_duration_seconds_bucket{le="2.5"} 1
+netatmo_refresh_duration_seconds_bucket{le="5"} 1
+netatmo_refresh_duration_seconds_bucket{le="10"} 1
+netatmo_refresh_duration_seconds_bucket{le="+Inf"} 1
+netatmo_refresh_duration_seconds_sum 0
+netatmo_refresh_duration_seconds_count 1
+# HELP netatmo_module_info Contains information about the module as labels, the value is always 1.
+# TYPE netatmo_module_info gauge
+netatmo_module_info{home="Home",id="aa:bb:cc:dd:ee:f0",module="Living Room",station="Home (Living Room)",type="NAMain"} 1
+netatmo_module_info{home="Home",id="aa:bb:cc:dd:ee:f1",module="Outside",station="Home (Living Room)",type="NAModule1"} 1
+netatmo_module_info{home="Home",id="aa:bb:cc:dd:ee:f2",module="Bedroom",station="Home (Living Room)",type="NAModule4"} 1
+netatmo_module_info{home="Home",id="aa:bb:cc:dd:ee:f3",module="id-aa:bb:cc:dd:ee:f3",station="Home (Living Room)",type="NAModule4"} 1
+netatmo_module_info{home="Home",id="aa:bb:cc:dd:ee:f4",module="Garden",station="Home (Living Room)",type="NAModule2"} 1
+# HELP netatmo_module_last_seen_time Contains the time the module was last seen by its station.
+# TYPE netatmo_module_last_seen_time gauge
+netatmo_module_last_seen_time{home="Home",module="Garden",station="Home (Living Room)"} 100
+netatmo_module_last_seen_time{home="Home",module="Outside",station="Home (Living Room)"} 3505
+# HELP netatmo_module_last_status_store_time Contains the time the last status of the module was stored by the NetAtmo cloud.
+# TYPE netatmo_module_last_status_store_time gauge
+netatmo_module_last_status_store_time{home="Home",module="Garden",station="Home (Living Room)"} 110
+netatmo_module_last_status_store_time{home="Home",module="Living Room",station="Home (Living Room)"} 3510
+netatmo_module_last_status_store_time{home="Home",module="Outside",station="Home (Living Room)"} 3506
+# HELP netatmo_module_reachable Set to 1 if the module is reachable by the NetAtmo cloud, 0 otherwise.
+# TYPE netatmo_module_reachable gauge
+netatmo_module_reachable{home="Home",module="Garden",station="Home (Living Room)"} 0
+netatmo_module_reachable{home="Home",module="Living Room",station="Home (Living Room)"} 1
+netatmo_module_reachable{home="Home",module="Outside",station="Home (Living Room)"} 1
 # HELP netatmo_refresh_interval_seconds Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.
 # TYPE netatmo_refresh_interval_seconds gauge
 netatmo_refresh_interval_seconds 3600
+# HELP netatmo_refresh_total Number of refresh tries by result.
+# TYPE netatmo_refresh_total counter
+netatmo_refresh_total{result="error"} 0
+netatmo_refresh_total{result="skipped"} 0
+netatmo_refresh_total{result="success"} 1
 # HELP netatmo_sensor_battery_percent Battery remaining life (10: low)
 # TYPE netatmo_sensor_battery_percent gauge
 netatmo_sensor_battery_percent{home="Home",module="Bedroom",station="Home (Living Room)"} 55
 netatmo_sensor_battery_percent{home="Home",module="Outside",station="Home (Living Room)"} 70
 netatmo_sensor_battery_percent{home="Home",module="id-aa:bb:cc:dd:ee:f3",station="Home (Living Room)"} 60
+# HELP netatmo_sensor_battery_state Battery state, the series with the current state is set to 1
+# TYPE netatmo_sensor_battery_state gauge
+netatmo_sensor_battery_state{home="Home",module="Bedroom",state="full",station="Home (Living Room)"} 0
+netatmo_sensor_battery_state{home="Home",module="Bedroom",state="high",station="Home (Living Room)"} 0
+netatmo_sensor_battery_state{home="Home",module="Bedroom",state="low",station="Home (Living Room)"} 1
+netatmo_sensor_battery_state{home="Home",module="Bedroom",state="medium",station="Home (Living Room)"} 0
+netatmo_sensor_battery_state{home="Home",module="Bedroom",state="very_low",station="Home (Living Room)"} 0
+netatmo_sensor_battery_state{home="Home",module="Outside",state="full",station="Home (Living Room)"} 0
+netatmo_sensor_battery_state{home="Home",module="Outside",state="high",station="Home (Living Room)"} 1
+netatmo_sensor_battery_state{home="Home",module="Outside",state="low",station="Home (Living Room)"} 0
+netatmo_sensor_battery_state{home="Home",module="Outside",state="medium",station="Home (Living Room)"} 0
+netatmo_sensor_battery_state{home="Home",module="Outside",state="very_low",station="Home (Living Room)"} 0
+# HELP netatmo_sensor_battery_voltage_volts Battery voltage in volts
+# TYPE netatmo_sensor_battery_voltage_volts gauge
+netatmo_sensor_battery_voltage_volts{home="Home",module="Bedroom",station="Home (Living Room)"} 4.7
+netatmo_sensor_battery_voltage_volts{home="Home",module="Outside",station="Home (Living Room)"} 5.2
 # HELP netatmo_sensor_co2_ppm Carbondioxide measurement in parts per million
 # TYPE netatmo_sensor_co2_ppm gauge
 netatmo_sensor_co2_ppm{home="Home",module="Bedroom",station="Home (Living Room)"} 510
@@ -221,9 +448,26 @@ netatmo_sensor_humidity_percent{home="Home",module="id-aa:bb:cc:dd:ee:f3",statio
 # HELP netatmo_sensor_noise_db Noise measurement in decibels
 # TYPE netatmo_sensor_noise_db gauge
 netatmo_sensor_noise_db{home="Home",module="Living Room",station="Home (Living Room)"} 40
+# HELP netatmo_sensor_absolute_pressure_mb Atmospheric pressure measurement at the altitude of the station in millibar
+# TYPE netatmo_sensor_absolute_pressure_mb gauge
+netatmo_sensor_absolute_pressure_mb{home="Home",module="Living Room",station="Home (Living Room)"} 987
 # HELP netatmo_sensor_pressure_mb Atmospheric pressure measurement in millibar
 # TYPE netatmo_sensor_pressure_mb gauge
 netatmo_sensor_pressure_mb{home="Home",module="Living Room",station="Home (Living Room)"} 1234
+# HELP netatmo_sensor_rf_quality RF signal quality derived from the signal strength, the series with the current quality is set to 1
+# TYPE netatmo_sensor_rf_quality gauge
+netatmo_sensor_rf_quality{home="Home",module="Bedroom",quality="full",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="Bedroom",quality="high",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="Bedroom",quality="low",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="Bedroom",quality="medium",station="Home (Living Room)"} 1
+netatmo_sensor_rf_quality{home="Home",module="Outside",quality="full",station="Home (Living Room)"} 1
+netatmo_sensor_rf_quality{home="Home",module="Outside",quality="high",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="Outside",quality="low",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="Outside",quality="medium",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="id-aa:bb:cc:dd:ee:f3",quality="full",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="id-aa:bb:cc:dd:ee:f3",quality="high",station="Home (Living Room)"} 1
+netatmo_sensor_rf_quality{home="Home",module="id-aa:bb:cc:dd:ee:f3",quality="low",station="Home (Living Room)"} 0
+netatmo_sensor_rf_quality{home="Home",module="id-aa:bb:cc:dd:ee:f3",quality="medium",station="Home (Living Room)"} 0
 # HELP netatmo_sensor_rf_signal_strength RF signal strength (90: lowest, 60: highest)
 # TYPE netatmo_sensor_rf_signal_strength gauge
 netatmo_sensor_rf_signal_strength{home="Home",module="Bedroom",station="Home (Living Room)"} 80
@@ -241,9 +485,17 @@ netatmo_sensor_updated{home="Home",module="Bedroom",station="Home (Living Room)"
 netatmo_sensor_updated{home="Home",module="Living Room",station="Home (Living Room)"} 3500
 netatmo_sensor_updated{home="Home",module="Outside",station="Home (Living Room)"} 3501
 netatmo_sensor_updated{home="Home",module="id-aa:bb:cc:dd:ee:f3",station="Home (Living Room)"} 3503
+# HELP netatmo_sensor_wifi_quality Wifi signal quality derived from the signal strength, the series with the current quality is set to 1
+# TYPE netatmo_sensor_wifi_quality gauge
+netatmo_sensor_wifi_quality{home="Home",module="Living Room",quality="average",station="Home (Living Room)"} 0
+netatmo_sensor_wifi_quality{home="Home",module="Living Room",quality="bad",station="Home (Living Room)"} 0
+netatmo_sensor_wifi_quality{home="Home",module="Living Room",quality="good",station="Home (Living Room)"} 1
 # HELP netatmo_sensor_wifi_signal_strength Wifi signal strength (86: bad, 71: avg, 56: good)
 # TYPE netatmo_sensor_wifi_signal_strength gauge
 netatmo_sensor_wifi_signal_strength{home="Home",module="Living Room",station="Home (Living Room)"} 45
+# HELP netatmo_station_last_setup_time Contains the time the station was last set up.
+# TYPE netatmo_station_last_setup_time gauge
+netatmo_station_last_setup_time{home="Home",station="Home (Living Room)"} 1000
 # HELP netatmo_up Zero if there was an error during the last refresh try.
 # TYPE netatmo_up gauge
 netatmo_up 1
@@ -260,12 +512,12 @@ netatmo_up 1
 				return time.Unix(3600, 0)
 			}
 
-			read := func() (*netatmo.DeviceCollection, error) {
+			read := func() (*api.DeviceCollection, error) {
 				return tc.data, nil
 			}
 			expected := strings.NewReader(tc.wantMetrics)
 
-			c := New(logrus.New(), read, time.Hour, time.Hour)
+			c := New(logrus.New(), read, time.Hour, time.Hour, Names{})
 			c.clock = mockClock
 			c.RefreshData(mockClock())
 
@@ -276,6 +528,381 @@ netatmo_up 1
 	}
 }
 
+func TestNetatmoCollector_CollectDerived(t *testing.T) {
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				Type:        "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature:      float32Ptr(20),
+					Humidity:         int32Ptr(50),
+					Pressure:         float32Ptr(1013.25),
+					AbsolutePressure: float32Ptr(898.75),
+					LastMeasure:      int64Ptr(3500),
+				},
+			},
+			Place: &api.Place{
+				Altitude: float64Ptr(1000),
+			},
+			LinkedModules: []*api.Device{
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f1",
+						ModuleName: "Outside",
+						Type:       "NAModule1",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(-10),
+							Humidity:    int32Ptr(70),
+							LastMeasure: int64Ptr(3501),
+						},
+					},
+				},
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f2",
+						ModuleName: "Wind",
+						Type:       "NAModule2",
+						DashboardData: netatmo.DashboardData{
+							WindStrength: int32Ptr(20),
+							LastMeasure:  int64Ptr(3502),
+						},
+					},
+				},
+				{
+					// A failed humidity sensor reports zero, which does not create derived metrics.
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f3",
+						ModuleName: "Bedroom",
+						Type:       "NAModule4",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(18),
+							Humidity:    int32Ptr(0),
+							LastMeasure: int64Ptr(3503),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	wantMetrics := `# HELP netatmo_derived_absolute_humidity_grams_per_cubic_meter Absolute humidity in grams per cubic meter, derived from temperature and humidity
+# TYPE netatmo_derived_absolute_humidity_grams_per_cubic_meter gauge
+netatmo_derived_absolute_humidity_grams_per_cubic_meter{home="Home",module="Living Room",station="Home (Living Room)"} 8.623006341966208
+netatmo_derived_absolute_humidity_grams_per_cubic_meter{home="Home",module="Outside",station="Home (Living Room)"} 1.6548650576402129
+# HELP netatmo_derived_dew_point_celsius Dew point in celsius, derived from temperature and humidity
+# TYPE netatmo_derived_dew_point_celsius gauge
+netatmo_derived_dew_point_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 9.255174598981256
+netatmo_derived_dew_point_celsius{home="Home",module="Outside",station="Home (Living Room)"} -14.438704051883779
+# HELP netatmo_derived_heat_index_celsius Heat index in celsius, derived from temperature and humidity
+# TYPE netatmo_derived_heat_index_celsius gauge
+netatmo_derived_heat_index_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 20
+netatmo_derived_heat_index_celsius{home="Home",module="Outside",station="Home (Living Room)"} -10
+# HELP netatmo_derived_humidex Humidex, derived from temperature and humidity
+# TYPE netatmo_derived_humidex gauge
+netatmo_derived_humidex{home="Home",module="Living Room",station="Home (Living Room)"} 20.941989071620913
+netatmo_derived_humidex{home="Home",module="Outside",station="Home (Living Room)"} -10
+# HELP netatmo_derived_pressure_qfe_mb Atmospheric pressure at the altitude of the station (QFE) in millibar, derived from the sea-level pressure and the altitude of the station
+# TYPE netatmo_derived_pressure_qfe_mb gauge
+netatmo_derived_pressure_qfe_mb{home="Home",module="Living Room",station="Home (Living Room)"} 898.7453975475726
+# HELP netatmo_derived_pressure_qnh_mb Atmospheric pressure reduced to sea level (QNH) in millibar, derived from the absolute pressure and the altitude of the station
+# TYPE netatmo_derived_pressure_qnh_mb gauge
+netatmo_derived_pressure_qnh_mb{home="Home",module="Living Room",station="Home (Living Room)"} 1013.2550717791813
+# HELP netatmo_derived_wind_chill_celsius Wind chill in celsius, derived from the temperature of the outdoor module and the wind strength of the wind gauge of the same station
+# TYPE netatmo_derived_wind_chill_celsius gauge
+netatmo_derived_wind_chill_celsius{home="Home",module="Outside",station="Home (Living Room)"} -17.86058434436593
+`
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	read := func() (*api.DeviceCollection, error) {
+		return testDevices, nil
+	}
+
+	c := New(logrus.New(), read, time.Hour, time.Hour, Names{})
+	c.clock = mockClock
+	c.DerivedMetrics = true
+	c.RefreshData(mockClock())
+
+	metricNames := []string{
+		"netatmo_derived_absolute_humidity_grams_per_cubic_meter",
+		"netatmo_derived_dew_point_celsius",
+		"netatmo_derived_heat_index_celsius",
+		"netatmo_derived_humidex",
+		"netatmo_derived_pressure_qfe_mb",
+		"netatmo_derived_pressure_qnh_mb",
+		"netatmo_derived_wind_chill_celsius",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNetatmoCollector_CollectImperial(t *testing.T) {
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				Type:        "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature:      float32Ptr(20),
+					Pressure:         float32Ptr(1013.25),
+					AbsolutePressure: float32Ptr(1000),
+					LastMeasure:      int64Ptr(3500),
+				},
+			},
+			LinkedModules: []*api.Device{
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f1",
+						ModuleName: "Wind",
+						Type:       "NAModule2",
+						DashboardData: netatmo.DashboardData{
+							WindStrength: int32Ptr(100),
+							LastMeasure:  int64Ptr(3501),
+						},
+					},
+				},
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f2",
+						ModuleName: "Rain",
+						Type:       "NAModule3",
+						DashboardData: netatmo.DashboardData{
+							Rain:        float32Ptr(25.4),
+							LastMeasure: int64Ptr(3502),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	wantMetrics := `# HELP netatmo_sensor_absolute_pressure_inhg Atmospheric pressure measurement at the altitude of the station in inches of mercury
+# TYPE netatmo_sensor_absolute_pressure_inhg gauge
+netatmo_sensor_absolute_pressure_inhg{home="Home",module="Living Room",station="Home (Living Room)"} 29.529983071445
+# HELP netatmo_sensor_pressure_inhg Atmospheric pressure measurement in inches of mercury
+# TYPE netatmo_sensor_pressure_inhg gauge
+netatmo_sensor_pressure_inhg{home="Home",module="Living Room",station="Home (Living Room)"} 29.921255347141646
+# HELP netatmo_sensor_rain_amount_inches Rain amount in inches
+# TYPE netatmo_sensor_rain_amount_inches gauge
+netatmo_sensor_rain_amount_inches{home="Home",module="Rain",station="Home (Living Room)"} 0.9999999849815069
+# HELP netatmo_sensor_temperature_fahrenheit Temperature measurement in fahrenheit
+# TYPE netatmo_sensor_temperature_fahrenheit gauge
+netatmo_sensor_temperature_fahrenheit{home="Home",module="Living Room",station="Home (Living Room)"} 68
+# HELP netatmo_sensor_wind_strength_mph Wind strength in miles per hour
+# TYPE netatmo_sensor_wind_strength_mph gauge
+netatmo_sensor_wind_strength_mph{home="Home",module="Wind",station="Home (Living Room)"} 62.13711922373339
+`
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	read := func() (*api.DeviceCollection, error) {
+		return testDevices, nil
+	}
+
+	c := New(logrus.New(), read, time.Hour, time.Hour, Names{})
+	c.clock = mockClock
+	c.UnitSystem = UnitSystemImperial
+	c.RefreshData(mockClock())
+
+	metricNames := []string{
+		"netatmo_sensor_absolute_pressure_inhg",
+		"netatmo_sensor_pressure_inhg",
+		"netatmo_sensor_rain_amount_inches",
+		"netatmo_sensor_temperature_fahrenheit",
+		"netatmo_sensor_wind_strength_mph",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNetatmoCollector_CollectNaming(t *testing.T) {
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				Type:        "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(20),
+					Pressure:    float32Ptr(1013),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+			LinkedModules: []*api.Device{
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f1",
+						ModuleName: "Wind",
+						Type:       "NAModule2",
+						DashboardData: netatmo.DashboardData{
+							WindStrength: int32Ptr(36),
+							LastMeasure:  int64Ptr(3501),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	v1Metrics := `# HELP netatmo_sensor_pressure_mb Atmospheric pressure measurement in millibar
+# TYPE netatmo_sensor_pressure_mb gauge
+netatmo_sensor_pressure_mb{home="Home",module="Living Room",station="Home (Living Room)"} 1013
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 20
+# HELP netatmo_sensor_wind_strength_kph Wind strength in kilometers per hour
+# TYPE netatmo_sensor_wind_strength_kph gauge
+netatmo_sensor_wind_strength_kph{home="Home",module="Wind",station="Home (Living Room)"} 36
+`
+	v2Metrics := `# HELP netatmo_sensor_pressure_pascals Atmospheric pressure measurement in pascals
+# TYPE netatmo_sensor_pressure_pascals gauge
+netatmo_sensor_pressure_pascals{home="Home",module="Living Room",station="Home (Living Room)"} 101300
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 20
+# HELP netatmo_sensor_wind_strength_meters_per_second Wind strength in meters per second
+# TYPE netatmo_sensor_wind_strength_meters_per_second gauge
+netatmo_sensor_wind_strength_meters_per_second{home="Home",module="Wind",station="Home (Living Room)"} 10
+`
+	dualMetrics := `# HELP netatmo_sensor_pressure_mb Atmospheric pressure measurement in millibar
+# TYPE netatmo_sensor_pressure_mb gauge
+netatmo_sensor_pressure_mb{home="Home",module="Living Room",station="Home (Living Room)"} 1013
+# HELP netatmo_sensor_pressure_pascals Atmospheric pressure measurement in pascals
+# TYPE netatmo_sensor_pressure_pascals gauge
+netatmo_sensor_pressure_pascals{home="Home",module="Living Room",station="Home (Living Room)"} 101300
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 20
+# HELP netatmo_sensor_wind_strength_kph Wind strength in kilometers per hour
+# TYPE netatmo_sensor_wind_strength_kph gauge
+netatmo_sensor_wind_strength_kph{home="Home",module="Wind",station="Home (Living Room)"} 36
+# HELP netatmo_sensor_wind_strength_meters_per_second Wind strength in meters per second
+# TYPE netatmo_sensor_wind_strength_meters_per_second gauge
+netatmo_sensor_wind_strength_meters_per_second{home="Home",module="Wind",station="Home (Living Room)"} 10
+`
+
+	metricNames := []string{
+		"netatmo_sensor_pressure_mb",
+		"netatmo_sensor_pressure_pascals",
+		"netatmo_sensor_temperature_celsius",
+		"netatmo_sensor_wind_strength_kph",
+		"netatmo_sensor_wind_strength_meters_per_second",
+	}
+
+	tt := []struct {
+		naming      MetricNaming
+		wantMetrics string
+	}{
+		{
+			naming:      MetricNamingV1,
+			wantMetrics: v1Metrics,
+		},
+		{
+			naming:      MetricNamingV2,
+			wantMetrics: v2Metrics,
+		},
+		{
+			naming:      MetricNamingDual,
+			wantMetrics: dualMetrics,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(string(tc.naming), func(t *testing.T) {
+			t.Parallel()
+
+			mockClock := func() time.Time {
+				return time.Unix(3600, 0)
+			}
+			read := func() (*api.DeviceCollection, error) {
+				return testDevices, nil
+			}
+
+			c := New(logrus.New(), read, time.Hour, time.Hour, Names{})
+			c.clock = mockClock
+			c.MetricNaming = tc.naming
+			c.RefreshData(mockClock())
+
+			if err := testutil.CollectAndCompare(c, strings.NewReader(tc.wantMetrics), metricNames...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestNetatmoCollector_CollectTimestamps(t *testing.T) {
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				WifiStatus:  int32Ptr(45),
+				Type:        "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(20),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+		},
+	}
+
+	wantMetrics := `# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 20 3500000
+# HELP netatmo_sensor_wifi_signal_strength Wifi signal strength (86: bad, 71: avg, 56: good)
+# TYPE netatmo_sensor_wifi_signal_strength gauge
+netatmo_sensor_wifi_signal_strength{home="Home",module="Living Room",station="Home (Living Room)"} 45
+`
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	read := func() (*api.DeviceCollection, error) {
+		return testDevices, nil
+	}
+
+	c := New(logrus.New(), read, time.Hour, time.Hour, Names{})
+	c.clock = mockClock
+	c.MeasurementTimestamps = true
+	c.RefreshData(mockClock())
+
+	metricNames := []string{
+		"netatmo_sensor_temperature_celsius",
+		"netatmo_sensor_wifi_signal_strength",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 func int32Ptr(i int32) *int32 {
 	return &i
 }
@@ -284,6 +911,10 @@ func int64Ptr(i int64) *int64 {
 	return &i
 }
 
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
 func float32Ptr(f float32) *float32 {
 	return &f
 }
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+var modulesFilteredDesc = newDesc(
+	prefix+"modules_filtered",
+	"Number of modules hidden by the module filters.",
+	nil)
+
+// ModuleRule matches modules by their properties. Every field contains a pattern, which is either a glob
+// or a regular expression enclosed in slashes, for example "/^Test/". Empty fields match every module.
+// A rule matches a module, if all non-empty fields match.
+type ModuleRule struct {
+	Name    string `yaml:"name"`
+	Station string `yaml:"station"`
+	Home    string `yaml:"home"`
+	ID      string `yaml:"id"`
+	Type    string `yaml:"type"`
+}
+
+// ModuleFilter decides which modules are exported.
+// If there are include rules, a module needs to match at least one of them. Modules matching an exclude rule are never exported.
+// A nil ModuleFilter includes all modules.
+type ModuleFilter struct {
+	include []compiledRule
+	exclude []compiledRule
+}
+
+// NewModuleFilter creates a ModuleFilter from the provided rules.
+func NewModuleFilter(include, exclude []ModuleRule) (*ModuleFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+
+	compiledInclude, err := compileRules(include)
+	if err != nil {
+		return nil, fmt.Errorf("error in include rules: %w", err)
+	}
+
+	compiledExclude, err := compileRules(exclude)
+	if err != nil {
+		return nil, fmt.Errorf("error in exclude rules: %w", err)
+	}
+
+	return &ModuleFilter{
+		include: compiledInclude,
+		exclude: compiledExclude,
+	}, nil
+}
+
+// Match returns true, if the module should be exported.
+func (f *ModuleFilter) Match(module *api.Device, stationName, homeName string) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.include) > 0 && !matchAny(f.include, module, stationName, homeName) {
+		return false
+	}
+
+	return !matchAny(f.exclude, module, stationName, homeName)
+}
+
+func matchAny(rules []compiledRule, module *api.Device, stationName, homeName string) bool {
+	for _, r := range rules {
+		if r.match(module, stationName, homeName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type matcher func(string) bool
+
+type compiledRule struct {
+	name    matcher
+	station matcher
+	home    matcher
+	id      matcher
+	typ     matcher
+}
+
+func (r compiledRule) match(module *api.Device, stationName, homeName string) bool {
+	return r.name(module.ModuleName) &&
+		r.station(stationName) &&
+		r.home(homeName) &&
+		r.id(module.ID) &&
+		r.typ(module.Type)
+}
+
+func compileRules(rules []ModuleRule) ([]compiledRule, error) {
+	result := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule == (ModuleRule{}) {
+			return nil, fmt.Errorf("rule %d: needs at least one pattern", i+1)
+		}
+
+		var compiled compiledRule
+		for _, field := range []struct {
+			pattern string
+			target  *matcher
+		}{
+			{rule.Name, &compiled.name},
+			{rule.Station, &compiled.station},
+			{rule.Home, &compiled.home},
+			{rule.ID, &compiled.id},
+			{rule.Type, &compiled.typ},
+		} {
+			m, err := compilePattern(field.pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			*field.target = m
+		}
+
+		result = append(result, compiled)
+	}
+
+	return result, nil
+}
+
+func compilePattern(pattern string) (matcher, error) {
+	switch {
+	case pattern == "":
+		return func(string) bool {
+			return true
+		}, nil
+	case len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+
+		return re.MatchString, nil
+	default:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+
+		return func(value string) bool {
+			matched, _ := path.Match(pattern, value)
+			return matched
+		}, nil
+	}
+}
@@ -0,0 +1,224 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestModuleFilter(t *testing.T) {
+	module := &api.Device{
+		Device: netatmo.Device{
+			ID:         "aa:bb:cc:dd:ee:f1",
+			ModuleName: "Test Outside",
+			Type:       "NAModule1",
+		},
+	}
+
+	tt := []struct {
+		desc      string
+		include   []ModuleRule
+		exclude   []ModuleRule
+		wantMatch bool
+		wantErr   string
+	}{
+		{
+			desc:      "no rules",
+			wantMatch: true,
+		},
+		{
+			desc: "include glob",
+			include: []ModuleRule{
+				{Station: "Home*"},
+			},
+			wantMatch: true,
+		},
+		{
+			desc: "include does not match",
+			include: []ModuleRule{
+				{Station: "Office*"},
+			},
+			wantMatch: false,
+		},
+		{
+			desc: "include needs all fields",
+			include: []ModuleRule{
+				{Station: "Home*", Type: "NAModule4"},
+			},
+			wantMatch: false,
+		},
+		{
+			desc: "one include matches",
+			include: []ModuleRule{
+				{Type: "NAModule4"},
+				{ID: "aa:bb:cc:dd:ee:*"},
+			},
+			wantMatch: true,
+		},
+		{
+			desc: "exclude regular expression",
+			exclude: []ModuleRule{
+				{Name: "/^Test /"},
+			},
+			wantMatch: false,
+		},
+		{
+			desc: "exclude wins over include",
+			include: []ModuleRule{
+				{Home: "Home"},
+			},
+			exclude: []ModuleRule{
+				{Type: "NAModule1"},
+			},
+			wantMatch: false,
+		},
+		{
+			desc: "exclude does not match",
+			exclude: []ModuleRule{
+				{Home: "Office"},
+			},
+			wantMatch: true,
+		},
+		{
+			desc: "empty rule",
+			exclude: []ModuleRule{
+				{},
+			},
+			wantErr: "error in exclude rules: rule 1: needs at least one pattern",
+		},
+		{
+			desc: "invalid regular expression",
+			include: []ModuleRule{
+				{Name: "/[/"},
+			},
+			wantErr: "error in include rules: rule 1: invalid regular expression \"/[/\": error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			desc: "invalid glob",
+			include: []ModuleRule{
+				{Name: "["},
+			},
+			wantErr: "error in include rules: rule 1: invalid glob \"[\": syntax error in pattern",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			filter, err := NewModuleFilter(tc.include, tc.exclude)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if match := filter.Match(module, "Home (Living Room)", "Home"); match != tc.wantMatch {
+				t.Errorf("got match %v, want %v", match, tc.wantMatch)
+			}
+		})
+	}
+}
+
+func TestNetatmoCollector_CollectFiltered(t *testing.T) {
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				Type:        "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(23),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+			LinkedModules: []*api.Device{
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f1",
+						ModuleName: "Outside",
+						Type:       "NAModule1",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(5),
+							LastMeasure: int64Ptr(3501),
+						},
+					},
+				},
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f2",
+						ModuleName: "Test Module",
+						Type:       "NAModule4",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(17),
+							LastMeasure: int64Ptr(3502),
+						},
+					},
+				},
+			},
+		},
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:e0",
+				ModuleName:  "Borrowed",
+				HomeName:    "Neighbor",
+				StationName: "Neighbor (Borrowed)",
+				Type:        "NAMain",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(21),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+		},
+	}
+
+	filter, err := NewModuleFilter([]ModuleRule{
+		{Home: "Home"},
+	}, []ModuleRule{
+		{Name: "/^Test/"},
+	})
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	c := New(logrus.New(), func() (*api.DeviceCollection, error) {
+		return testDevices, nil
+	}, time.Hour, time.Hour, Names{})
+	c.clock = mockClock
+	c.ModuleFilter = filter
+	c.RefreshData(mockClock())
+
+	wantMetrics := `# HELP netatmo_modules_filtered Number of modules hidden by the module filters.
+# TYPE netatmo_modules_filtered gauge
+netatmo_modules_filtered 2
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 23
+netatmo_sensor_temperature_celsius{home="Home",module="Outside",station="Home (Living Room)"} 5
+`
+	metricNames := []string{
+		"netatmo_modules_filtered",
+		"netatmo_sensor_temperature_celsius",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"golang.org/x/oauth2"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+// DefaultRefreshHistorySize is the number of refresh attempts kept in the history by default.
+const DefaultRefreshHistorySize = 20
+
+const (
+	errorClassNotAuthenticated = "not_authenticated"
+	errorClassBudgetExhausted  = "budget_exhausted"
+	errorClassAPI              = "api"
+	errorClassTokenRefresh     = "token_refresh"
+	errorClassTimeout          = "timeout"
+	errorClassNetwork          = "network"
+	errorClassDecode           = "decode"
+	errorClassUnknown          = "unknown"
+)
+
+// RefreshAttempt contains information about a single try to refresh the data from the NetAtmo API.
+type RefreshAttempt struct {
+	Start      time.Time
+	Duration   time.Duration
+	Error      error
+	ErrorClass string
+	Devices    int
+	Modules    int
+}
+
+// Success returns true, if the refresh did not produce an error.
+func (a RefreshAttempt) Success() bool {
+	return a.Error == nil
+}
+
+func (a RefreshAttempt) MarshalJSON() ([]byte, error) {
+	data := struct {
+		Start      time.Time `json:"start"`
+		Duration   string    `json:"duration"`
+		Success    bool      `json:"success"`
+		Error      string    `json:"error,omitempty"`
+		ErrorClass string    `json:"errorClass,omitempty"`
+		Devices    int       `json:"devices"`
+		Modules    int       `json:"modules"`
+	}{
+		Start:      a.Start,
+		Duration:   a.Duration.String(),
+		Success:    a.Success(),
+		ErrorClass: a.ErrorClass,
+		Devices:    a.Devices,
+		Modules:    a.Modules,
+	}
+	if a.Error != nil {
+		data.Error = a.Error.Error()
+	}
+
+	return json.Marshal(data)
+}
+
+// refreshHistory is a ring buffer holding the latest refresh attempts.
+type refreshHistory struct {
+	lock     sync.RWMutex
+	attempts []RefreshAttempt
+	next     int
+	full     bool
+}
+
+// add stores an attempt in the history, replacing the oldest one once size attempts are stored.
+// A change of the size discards the previous history.
+func (h *refreshHistory) add(size int, attempt RefreshAttempt) {
+	if size <= 0 {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.attempts) != size {
+		h.attempts = make([]RefreshAttempt, size)
+		h.next = 0
+		h.full = false
+	}
+
+	h.attempts[h.next] = attempt
+	h.next = (h.next + 1) % size
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the stored attempts, newest first.
+func (h *refreshHistory) list() []RefreshAttempt {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	count := h.next
+	if h.full {
+		count = len(h.attempts)
+	}
+
+	result := make([]RefreshAttempt, 0, count)
+	for i := 1; i <= count; i++ {
+		result = append(result, h.attempts[(h.next-i+len(h.attempts))%len(h.attempts)])
+	}
+
+	return result
+}
+
+// errorClass sorts errors returned by the ReadFunction into a few broad classes.
+func errorClass(err error) string {
+	var apiErr *api.Error
+	var retrieveErr *oauth2.RetrieveError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, netatmo.ErrNotAuthenticated):
+		return errorClassNotAuthenticated
+	case errors.Is(err, api.ErrBudgetExhausted):
+		return errorClassBudgetExhausted
+	case errors.As(err, &apiErr):
+		return errorClassAPI
+	case errors.As(err, &retrieveErr):
+		return errorClassTokenRefresh
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errorClassTimeout
+	case errors.As(err, &netErr):
+		return errorClassNetwork
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return errorClassDecode
+	default:
+		return errorClassUnknown
+	}
+}
@@ -0,0 +1,170 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestRefreshHistory(t *testing.T) {
+	attempt := func(i int) RefreshAttempt {
+		return RefreshAttempt{
+			Start: time.Unix(int64(i), 0),
+		}
+	}
+	starts := func(attempts []RefreshAttempt) []int64 {
+		result := []int64{}
+		for _, a := range attempts {
+			result = append(result, a.Start.Unix())
+		}
+		return result
+	}
+
+	tt := []struct {
+		desc       string
+		size       int
+		add        int
+		wantStarts []int64
+	}{
+		{
+			desc:       "empty",
+			size:       3,
+			add:        0,
+			wantStarts: []int64{},
+		},
+		{
+			desc:       "partially filled",
+			size:       3,
+			add:        2,
+			wantStarts: []int64{2, 1},
+		},
+		{
+			desc:       "full",
+			size:       3,
+			add:        3,
+			wantStarts: []int64{3, 2, 1},
+		},
+		{
+			desc:       "wrapped",
+			size:       3,
+			add:        5,
+			wantStarts: []int64{5, 4, 3},
+		},
+		{
+			desc:       "disabled",
+			size:       0,
+			add:        5,
+			wantStarts: []int64{},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var h refreshHistory
+			for i := 1; i <= tc.add; i++ {
+				h.add(tc.size, attempt(i))
+			}
+
+			if diff := cmp.Diff(starts(h.list()), tc.wantStarts); diff != "" {
+				t.Errorf("history differs: -got+want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRefreshAttemptJSON(t *testing.T) {
+	attempt := RefreshAttempt{
+		Start:      time.Unix(3600, 0).UTC(),
+		Duration:   1500 * time.Millisecond,
+		Error:      errors.New("test error"),
+		ErrorClass: errorClassUnknown,
+	}
+
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	want := `{"start":"1970-01-01T01:00:00Z","duration":"1.5s","success":false,"error":"test error","errorClass":"unknown","devices":0,"modules":0}`
+	if diff := cmp.Diff(string(data), want); diff != "" {
+		t.Errorf("json differs: -got+want\n%s", diff)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tt := []struct {
+		desc string
+		err  error
+		want string
+	}{
+		{
+			desc: "no error",
+			err:  nil,
+			want: "",
+		},
+		{
+			desc: "not authenticated",
+			err:  netatmo.ErrNotAuthenticated,
+			want: errorClassNotAuthenticated,
+		},
+		{
+			desc: "budget exhausted",
+			err:  api.ErrBudgetExhausted,
+			want: errorClassBudgetExhausted,
+		},
+		{
+			desc: "api error",
+			err:  &api.Error{StatusCode: 403, Code: 3, Message: "Access token expired"},
+			want: errorClassAPI,
+		},
+		{
+			desc: "token refresh",
+			err:  &url.Error{Op: "Get", URL: "https://example.com", Err: &oauth2.RetrieveError{}},
+			want: errorClassTokenRefresh,
+		},
+		{
+			desc: "timeout",
+			err:  &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded},
+			want: errorClassTimeout,
+		},
+		{
+			desc: "network",
+			err:  &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection refused")},
+			want: errorClassNetwork,
+		},
+		{
+			desc: "decode",
+			err:  fmt.Errorf("decoding: %w", &json.SyntaxError{}),
+			want: errorClassDecode,
+		},
+		{
+			desc: "unknown",
+			err:  errors.New("test error"),
+			want: errorClassUnknown,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errorClass(tc.err); got != tc.want {
+				t.Errorf("got class %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultPrefix is the prefix of all metric names, unless a different prefix is configured.
+const DefaultPrefix = "netatmo_"
+
+// Names configures the names used in the metrics of the collector.
+type Names struct {
+	// Prefix replaces DefaultPrefix in the metric names. An empty prefix uses DefaultPrefix.
+	Prefix string
+	// Labels maps the labels used by the exporter, like "station", to different names.
+	Labels map[string]string
+}
+
+// Validate checks that the prefix and label names are valid and that no two labels have the same name.
+func (n Names) Validate() error {
+	if n.Prefix != "" && !metricPrefixRegexp.MatchString(n.Prefix) {
+		return fmt.Errorf("invalid metric prefix %q", n.Prefix)
+	}
+
+	renamed := make([]string, 0, len(n.Labels))
+	for label := range n.Labels {
+		renamed = append(renamed, label)
+	}
+	sort.Strings(renamed)
+
+	for _, label := range renamed {
+		if !slices.Contains(exporterLabels, label) {
+			return fmt.Errorf("unknown label %q", label)
+		}
+
+		if name := n.Labels[label]; !ValidLabelName(name) {
+			return fmt.Errorf("invalid name %q for label %q", name, label)
+		}
+	}
+
+	used := make(map[string]string, len(exporterLabels))
+	for _, label := range exporterLabels {
+		name := n.label(label)
+		if other, ok := used[name]; ok {
+			return fmt.Errorf("labels %q and %q both use the name %q", other, label, name)
+		}
+		used[name] = label
+	}
+
+	return nil
+}
+
+func (n Names) metricName(name string) string {
+	if n.Prefix == "" {
+		return name
+	}
+
+	return n.Prefix + strings.TrimPrefix(name, DefaultPrefix)
+}
+
+func (n Names) label(label string) string {
+	if name, ok := n.Labels[label]; ok {
+		return name
+	}
+
+	return label
+}
+
+func (n Names) labels(labels []string) []string {
+	result := make([]string, 0, len(labels))
+	for _, label := range labels {
+		result = append(result, n.label(label))
+	}
+
+	return result
+}
+
+// ValidLabelName returns true, if the name can be used as label name. Names starting with "__" are reserved.
+func ValidLabelName(name string) bool {
+	return labelNameRegexp.MatchString(name) && !strings.HasPrefix(name, "__")
+}
+
+// descSpec contains the parameters used for creating a descriptor, so that variants of it can be created later.
+type descSpec struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// descSpecs contains the specification of all descriptors created using newDesc.
+var descSpecs = map[*prometheus.Desc]descSpec{}
+
+// newDesc creates a descriptor and remembers its specification.
+func newDesc(name, help string, labels []string) *prometheus.Desc {
+	desc := prometheus.NewDesc(name, help, labels, nil)
+	descSpecs[desc] = descSpec{
+		name:   name,
+		help:   help,
+		labels: labels,
+	}
+
+	return desc
+}
+
+var moduleInfoDesc = newDesc(
+	modulePrefix+"info",
+	"Contains information about the module as labels, the value is always 1.",
+	slices.Concat(varLabels, []string{"id", "type"}))
+
+var (
+	labelNameRegexp    = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+	metricPrefixRegexp = regexp.MustCompile("^[a-zA-Z_:][a-zA-Z0-9_:]*$")
+)
+
+// exporterLabels contains the labels used by the exporter. They can be renamed, but not used as custom module labels.
+var exporterLabels = []string{
+	"module",
+	"station",
+	"home",
+	"id",
+	"type",
+	"state",
+	"quality",
+	"measurement",
+	"reason",
+}
+
+// SetModuleLabels configures additional labels attached to all metrics of a module.
+// The keys of the map are module IDs, the values contain the label names and values for that module.
+// Modules without a value for a label get an empty value.
+func (c *NetatmoCollector) SetModuleLabels(moduleLabels map[string]map[string]string) error {
+	names, err := moduleLabelNames(moduleLabels, c.names)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string][]string, len(moduleLabels))
+	for id, labels := range moduleLabels {
+		moduleValues := make([]string, 0, len(names))
+		for _, name := range names {
+			moduleValues = append(moduleValues, labels[name])
+		}
+		values[strings.ToLower(id)] = moduleValues
+	}
+
+	c.extraLabelNames = names
+	c.extraLabelValues = values
+	c.buildDescs()
+	return nil
+}
+
+// ValidateModuleLabels checks that the custom module labels can be used as label names.
+func ValidateModuleLabels(moduleLabels map[string]map[string]string, names Names) error {
+	_, err := moduleLabelNames(moduleLabels, names)
+	return err
+}
+
+func moduleLabelNames(moduleLabels map[string]map[string]string, names Names) ([]string, error) {
+	reserved := names.labels(exporterLabels)
+	result := []string{}
+	for id, labels := range moduleLabels {
+		for name := range labels {
+			switch {
+			case !ValidLabelName(name):
+				return nil, fmt.Errorf("module %s: invalid label name %q", id, name)
+			case slices.Contains(reserved, name):
+				return nil, fmt.Errorf("module %s: label name %q is reserved", id, name)
+			case !slices.Contains(result, name):
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+func hasModuleLabels(labels []string) bool {
+	return len(labels) >= len(varLabels) && slices.Equal(labels[:len(varLabels)], varLabels)
+}
+
+// moduleLabels returns the label values for metrics of a module, including the custom labels.
+func (c *NetatmoCollector) moduleLabels(id, moduleName, stationName, homeName string) []string {
+	labels := []string{moduleName, stationName, homeName}
+	if len(c.extraLabelNames) == 0 {
+		return labels
+	}
+
+	extra, ok := c.extraLabelValues[strings.ToLower(id)]
+	if !ok {
+		extra = make([]string, len(c.extraLabelNames))
+	}
+
+	return append(labels, extra...)
+}
+
+// buildDescs creates the descriptors used by the collector from the package-level descriptors,
+// applying the configured names and custom module labels.
+func (c *NetatmoCollector) buildDescs() {
+	descs := make(map[*prometheus.Desc]*prometheus.Desc, len(descSpecs))
+	for desc, spec := range descSpecs {
+		labels := c.names.labels(spec.labels)
+		if len(c.extraLabelNames) > 0 && hasModuleLabels(spec.labels) {
+			labels = slices.Concat(labels[:len(varLabels)], c.extraLabelNames, labels[len(varLabels):])
+		}
+
+		descs[desc] = prometheus.NewDesc(c.names.metricName(spec.name), spec.help, labels, nil)
+	}
+
+	c.descs = descs
+}
+
+// desc returns the descriptor to use for sending a metric, which has the configured names and custom labels.
+func (c *NetatmoCollector) desc(desc *prometheus.Desc) *prometheus.Desc {
+	if extended, ok := c.descs[desc]; ok {
+		return extended
+	}
+
+	return desc
+}
@@ -0,0 +1,315 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestSetModuleLabels(t *testing.T) {
+	tt := []struct {
+		desc    string
+		labels  map[string]map[string]string
+		wantErr string
+	}{
+		{
+			desc: "no labels",
+		},
+		{
+			desc: "valid labels",
+			labels: map[string]map[string]string{
+				"aa:bb:cc:dd:ee:f0": {
+					"room":  "Living Room",
+					"floor": "1",
+				},
+			},
+		},
+		{
+			desc: "invalid name",
+			labels: map[string]map[string]string{
+				"aa:bb:cc:dd:ee:f0": {
+					"indoor/outdoor": "indoor",
+				},
+			},
+			wantErr: "module aa:bb:cc:dd:ee:f0: invalid label name \"indoor/outdoor\"",
+		},
+		{
+			desc: "internal name",
+			labels: map[string]map[string]string{
+				"aa:bb:cc:dd:ee:f0": {
+					"__room": "Living Room",
+				},
+			},
+			wantErr: "module aa:bb:cc:dd:ee:f0: invalid label name \"__room\"",
+		},
+		{
+			desc: "reserved name",
+			labels: map[string]map[string]string{
+				"aa:bb:cc:dd:ee:f0": {
+					"home": "Office",
+				},
+			},
+			wantErr: "module aa:bb:cc:dd:ee:f0: label name \"home\" is reserved",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			c := New(logrus.New(), nil, time.Hour, time.Hour, Names{})
+			err := c.SetModuleLabels(tc.labels)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %q, want none", err)
+			}
+		})
+	}
+}
+
+func TestNetatmoCollector_CollectModuleLabels(t *testing.T) {
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				Type:        "NAMain",
+				WifiStatus:  int32Ptr(45),
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(23),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+			LinkedModules: []*api.Device{
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f1",
+						ModuleName: "Outside",
+						Type:       "NAModule1",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(5),
+							LastMeasure: int64Ptr(3501),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	c := New(logrus.New(), func() (*api.DeviceCollection, error) {
+		return testDevices, nil
+	}, time.Hour, time.Hour, Names{})
+	c.clock = mockClock
+	if err := c.SetModuleLabels(map[string]map[string]string{
+		"AA:BB:CC:DD:EE:F0": {
+			"room":  "Living Room",
+			"floor": "1",
+		},
+		"aa:bb:cc:dd:ee:f1": {
+			"zone": "outdoor",
+		},
+	}); err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+	c.RefreshData(mockClock())
+
+	wantMetrics := `# HELP netatmo_module_info Contains information about the module as labels, the value is always 1.
+# TYPE netatmo_module_info gauge
+netatmo_module_info{floor="",home="Home",id="aa:bb:cc:dd:ee:f1",module="Outside",room="",station="Home (Living Room)",type="NAModule1",zone="outdoor"} 1
+netatmo_module_info{floor="1",home="Home",id="aa:bb:cc:dd:ee:f0",module="Living Room",room="Living Room",station="Home (Living Room)",type="NAMain",zone=""} 1
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{floor="",home="Home",module="Outside",room="",station="Home (Living Room)",zone="outdoor"} 5
+netatmo_sensor_temperature_celsius{floor="1",home="Home",module="Living Room",room="Living Room",station="Home (Living Room)",zone=""} 23
+# HELP netatmo_sensor_wifi_quality Wifi signal quality derived from the signal strength, the series with the current quality is set to 1
+# TYPE netatmo_sensor_wifi_quality gauge
+netatmo_sensor_wifi_quality{floor="1",home="Home",module="Living Room",quality="average",room="Living Room",station="Home (Living Room)",zone=""} 0
+netatmo_sensor_wifi_quality{floor="1",home="Home",module="Living Room",quality="bad",room="Living Room",station="Home (Living Room)",zone=""} 0
+netatmo_sensor_wifi_quality{floor="1",home="Home",module="Living Room",quality="good",room="Living Room",station="Home (Living Room)",zone=""} 1
+`
+	metricNames := []string{
+		"netatmo_module_info",
+		"netatmo_sensor_temperature_celsius",
+		"netatmo_sensor_wifi_quality",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNamesValidate(t *testing.T) {
+	tt := []struct {
+		desc    string
+		names   Names
+		wantErr string
+	}{
+		{
+			desc: "default",
+		},
+		{
+			desc: "valid names",
+			names: Names{
+				Prefix: "weather_",
+				Labels: map[string]string{
+					"station": "site",
+					"module":  "sensor",
+				},
+			},
+		},
+		{
+			desc: "swap labels",
+			names: Names{
+				Labels: map[string]string{
+					"station": "home",
+					"home":    "station",
+				},
+			},
+		},
+		{
+			desc: "invalid prefix",
+			names: Names{
+				Prefix: "weather-",
+			},
+			wantErr: "invalid metric prefix \"weather-\"",
+		},
+		{
+			desc: "unknown label",
+			names: Names{
+				Labels: map[string]string{
+					"room": "location",
+				},
+			},
+			wantErr: "unknown label \"room\"",
+		},
+		{
+			desc: "invalid label name",
+			names: Names{
+				Labels: map[string]string{
+					"station": "__site",
+				},
+			},
+			wantErr: "invalid name \"__site\" for label \"station\"",
+		},
+		{
+			desc: "duplicate label name",
+			names: Names{
+				Labels: map[string]string{
+					"module": "home",
+				},
+			},
+			wantErr: "labels \"module\" and \"home\" both use the name \"home\"",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.names.Validate()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %q, want none", err)
+			}
+		})
+	}
+}
+
+func TestNetatmoCollector_CollectNames(t *testing.T) {
+	testDevices := &api.DeviceCollection{}
+	testDevices.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				Type:        "NAMain",
+				WifiStatus:  int32Ptr(45),
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(23),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+		},
+	}
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	c := New(logrus.New(), func() (*api.DeviceCollection, error) {
+		return testDevices, nil
+	}, time.Hour, time.Hour, Names{
+		Prefix: "weather_",
+		Labels: map[string]string{
+			"station": "site",
+			"module":  "sensor",
+			"quality": "level",
+		},
+	})
+	c.clock = mockClock
+	if err := c.SetModuleLabels(map[string]map[string]string{
+		"aa:bb:cc:dd:ee:f0": {
+			"station": "Kitchen",
+		},
+	}); err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+	c.RefreshData(mockClock())
+
+	wantMetrics := `# HELP weather_refresh_total Number of refresh tries by result.
+# TYPE weather_refresh_total counter
+weather_refresh_total{result="error"} 0
+weather_refresh_total{result="skipped"} 0
+weather_refresh_total{result="success"} 1
+# HELP weather_modules_filtered Number of modules hidden by the module filters.
+# TYPE weather_modules_filtered gauge
+weather_modules_filtered 0
+# HELP weather_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE weather_sensor_temperature_celsius gauge
+weather_sensor_temperature_celsius{home="Home",sensor="Living Room",site="Home (Living Room)",station="Kitchen"} 23
+# HELP weather_sensor_wifi_quality Wifi signal quality derived from the signal strength, the series with the current quality is set to 1
+# TYPE weather_sensor_wifi_quality gauge
+weather_sensor_wifi_quality{home="Home",level="average",sensor="Living Room",site="Home (Living Room)",station="Kitchen"} 0
+weather_sensor_wifi_quality{home="Home",level="bad",sensor="Living Room",site="Home (Living Room)",station="Kitchen"} 0
+weather_sensor_wifi_quality{home="Home",level="good",sensor="Living Room",site="Home (Living Room)",station="Kitchen"} 1
+# HELP weather_up Zero if there was an error during the last refresh try.
+# TYPE weather_up gauge
+weather_up 1
+`
+	metricNames := []string{
+		"weather_modules_filtered",
+		"weather_refresh_total",
+		"weather_sensor_temperature_celsius",
+		"weather_sensor_wifi_quality",
+		"weather_up",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,99 @@
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// MetricNaming selects the naming scheme used for the sensor metrics.
+type MetricNaming string
+
+const (
+	// MetricNamingV1 uses the original metric names, which contain the units provided by NetAtmo.
+	MetricNamingV1 MetricNaming = "v1"
+	// MetricNamingV2 uses metric names with base units following the Prometheus naming conventions.
+	MetricNamingV2 MetricNaming = "v2"
+	// MetricNamingDual exports both the v1 and v2 names, so that dashboards can be migrated gradually.
+	MetricNamingDual MetricNaming = "dual"
+)
+
+// Valid returns true if the naming scheme is known.
+func (n MetricNaming) Valid() bool {
+	switch n {
+	case MetricNamingV1, MetricNamingV2, MetricNamingDual:
+		return true
+	default:
+		return false
+	}
+}
+
+// v2Metric contains the base-unit descriptor for a v1 metric and the factor to convert the v1 value to the base unit.
+type v2Metric struct {
+	desc   *prometheus.Desc
+	factor float64
+}
+
+var (
+	pressurePascalsDesc = newDesc(
+		sensorPrefix+"pressure_pascals",
+		"Atmospheric pressure measurement in pascals",
+		varLabels)
+
+	absolutePressurePascalsDesc = newDesc(
+		sensorPrefix+"absolute_pressure_pascals",
+		"Atmospheric pressure measurement at the altitude of the station in pascals",
+		varLabels)
+
+	windStrengthMetersPerSecondDesc = newDesc(
+		sensorPrefix+"wind_strength_meters_per_second",
+		"Wind strength in meters per second",
+		varLabels)
+
+	noiseDecibelsDesc = newDesc(
+		sensorPrefix+"noise_decibels",
+		"Noise measurement in decibels",
+		varLabels)
+
+	rainMetersDesc = newDesc(
+		sensorPrefix+"rain_amount_meters",
+		"Rain amount in meters",
+		varLabels)
+
+	humidityRatioDesc = newDesc(
+		sensorPrefix+"humidity_ratio",
+		"Relative humidity measurement as a ratio between 0 and 1",
+		varLabels)
+
+	batteryRatioDesc = newDesc(
+		sensorPrefix+"battery_ratio",
+		"Battery remaining life as a ratio between 0 and 1 (0.1: low)",
+		varLabels)
+
+	qnhPascalsDesc = newDesc(
+		derivedPrefix+"pressure_qnh_pascals",
+		"Atmospheric pressure reduced to sea level (QNH) in pascals, derived from the absolute pressure and the altitude of the station",
+		varLabels)
+
+	qfePascalsDesc = newDesc(
+		derivedPrefix+"pressure_qfe_pascals",
+		"Atmospheric pressure at the altitude of the station (QFE) in pascals, derived from the sea-level pressure and the altitude of the station",
+		varLabels)
+
+	// v2Metrics maps the v1 descriptors, which do not use base units, to their v2 counterparts.
+	// Metrics not contained in this map have the same name in both naming schemes.
+	v2Metrics = map[*prometheus.Desc]v2Metric{
+		pressureDesc:         {desc: pressurePascalsDesc, factor: 100},
+		absolutePressureDesc: {desc: absolutePressurePascalsDesc, factor: 100},
+		windStrengthDesc:     {desc: windStrengthMetersPerSecondDesc, factor: 1 / 3.6},
+		noiseDesc:            {desc: noiseDecibelsDesc, factor: 1},
+		rainDesc:             {desc: rainMetersDesc, factor: 0.001},
+		humidityDesc:         {desc: humidityRatioDesc, factor: 0.01},
+		batteryDesc:          {desc: batteryRatioDesc, factor: 0.01},
+		qnhDesc:              {desc: qnhPascalsDesc, factor: 100},
+		qfeDesc:              {desc: qfePascalsDesc, factor: 100},
+
+		pressureRawDesc:         {desc: pressurePascalsRawDesc, factor: 100},
+		absolutePressureRawDesc: {desc: absolutePressurePascalsRawDesc, factor: 100},
+		windStrengthRawDesc:     {desc: windStrengthMetersPerSecondRawDesc, factor: 1 / 3.6},
+		noiseRawDesc:            {desc: noiseDecibelsRawDesc, factor: 1},
+		rainRawDesc:             {desc: rainMetersRawDesc, factor: 0.001},
+		humidityRawDesc:         {desc: humidityRatioRawDesc, factor: 0.01},
+	}
+)
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+// cacheSnapshot is the on-disk format of the cached data.
+type cacheSnapshot struct {
+	Timestamp time.Time             `json:"timestamp"`
+	Data      *api.DeviceCollection `json:"data"`
+}
+
+// LoadCache restores the cached data from the CacheFile.
+// Data older than the stale threshold is ignored, as it would not produce any sensor metrics anyway.
+// A missing file is not an error.
+func (c *NetatmoCollector) LoadCache() error {
+	if c.CacheFile == "" {
+		return nil
+	}
+
+	file, err := os.Open(c.CacheFile)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	default:
+	}
+	defer file.Close()
+
+	var snapshot cacheSnapshot
+	if err := json.NewDecoder(file).Decode(&snapshot); err != nil {
+		return fmt.Errorf("error decoding cache file: %w", err)
+	}
+
+	if snapshot.Data == nil {
+		return nil
+	}
+
+	age := c.clock().Sub(snapshot.Timestamp)
+	if age > c.StaleThreshold {
+		c.Log.Infof("Ignoring cache file, because the data is too old: %s", age)
+		return nil
+	}
+
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	c.cacheTimestamp = snapshot.Timestamp
+	c.cachedData = snapshot.Data
+
+	c.Log.Infof("Restored cached data from %s (age %s).", c.CacheFile, age)
+	return nil
+}
+
+// saveCache writes the cached data to the CacheFile. The file is replaced atomically,
+// so that a crash during writing does not leave a corrupt file behind.
+func (c *NetatmoCollector) saveCache(timestamp time.Time, data *api.DeviceCollection) error {
+	if c.CacheFile == "" {
+		return nil
+	}
+
+	contents, err := json.Marshal(cacheSnapshot{
+		Timestamp: timestamp,
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling cache: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(c.CacheFile), filepath.Base(c.CacheFile)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary cache file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(contents); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error syncing cache file: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("error closing cache file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), c.CacheFile); err != nil {
+		return fmt.Errorf("error replacing cache file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestPersistCache(t *testing.T) {
+	testData := &api.DeviceCollection{}
+	testData.Body.Devices = []*api.Device{
+		{
+			Device: netatmo.Device{
+				ID:          "aa:bb:cc:dd:ee:f0",
+				ModuleName:  "Living Room",
+				HomeName:    "Home",
+				StationName: "Home (Living Room)",
+				DashboardData: netatmo.DashboardData{
+					Temperature: float32Ptr(23),
+					LastMeasure: int64Ptr(3500),
+				},
+			},
+			Reachable: boolPtr(true),
+			LinkedModules: []*api.Device{
+				{
+					Device: netatmo.Device{
+						ID:         "aa:bb:cc:dd:ee:f1",
+						ModuleName: "Outside",
+						DashboardData: netatmo.DashboardData{
+							Temperature: float32Ptr(5),
+							LastMeasure: int64Ptr(3501),
+						},
+					},
+					BatteryVP: int32Ptr(5200),
+				},
+			},
+		},
+	}
+
+	tt := []struct {
+		desc          string
+		loadTime      time.Time
+		wantTimestamp time.Time
+		wantData      *api.DeviceCollection
+	}{
+		{
+			desc:          "restored",
+			loadTime:      time.Unix(4200, 0),
+			wantTimestamp: time.Unix(3600, 0),
+			wantData:      testData,
+		},
+		{
+			desc:          "too old",
+			loadTime:      time.Unix(10800, 0),
+			wantTimestamp: time.Time{},
+			wantData:      nil,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			cacheFile := filepath.Join(t.TempDir(), "cache.json")
+
+			c := New(logrus.New(), func() (*api.DeviceCollection, error) {
+				return testData, nil
+			}, time.Minute, time.Hour, Names{})
+			c.CacheFile = cacheFile
+			c.RefreshData(time.Unix(3600, 0))
+
+			info, err := os.Stat(cacheFile)
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("got file mode %s, want %s", info.Mode().Perm(), os.FileMode(0o600))
+			}
+
+			restored := New(logrus.New(), nil, time.Minute, time.Hour, Names{})
+			restored.CacheFile = cacheFile
+			restored.clock = func() time.Time {
+				return tc.loadTime
+			}
+			if err := restored.LoadCache(); err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if !restored.cacheTimestamp.Equal(tc.wantTimestamp) {
+				t.Errorf("got timestamp %s, want %s", restored.cacheTimestamp, tc.wantTimestamp)
+			}
+
+			if diff := cmp.Diff(restored.cachedData, tc.wantData); diff != "" {
+				t.Errorf("data differs: -got+want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadCacheMissingFile(t *testing.T) {
+	c := New(logrus.New(), nil, time.Minute, time.Hour, Names{})
+	c.CacheFile = filepath.Join(t.TempDir(), "missing.json")
+
+	if err := c.LoadCache(); err != nil {
+		t.Errorf("got error %q, want none", err)
+	}
+
+	if c.cachedData != nil {
+		t.Errorf("got data %v, want none", c.cachedData)
+	}
+}
@@ -0,0 +1,303 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+var sensorRejectedDesc = newDesc(
+	sensorPrefix+"rejected_total",
+	"Number of sensor values rejected by the plausibility checks.",
+	slices.Concat(varLabels, []string{"measurement", "reason"}))
+
+const (
+	rejectReasonTooLow  = "too_low"
+	rejectReasonTooHigh = "too_high"
+	rejectReasonJump    = "jump"
+)
+
+// measurement provides access to a sensor value of the dashboard data.
+type measurement struct {
+	name  string
+	value func(data netatmo.DashboardData) *float64
+	clear func(data *netatmo.DashboardData)
+}
+
+var measurements = []measurement{
+	{
+		name:  "temperature",
+		value: func(data netatmo.DashboardData) *float64 { return floatValue(data.Temperature) },
+		clear: func(data *netatmo.DashboardData) { data.Temperature = nil },
+	},
+	{
+		name:  "humidity",
+		value: func(data netatmo.DashboardData) *float64 { return intValue(data.Humidity) },
+		clear: func(data *netatmo.DashboardData) { data.Humidity = nil },
+	},
+	{
+		name:  "co2",
+		value: func(data netatmo.DashboardData) *float64 { return intValue(data.CO2) },
+		clear: func(data *netatmo.DashboardData) { data.CO2 = nil },
+	},
+	{
+		name:  "noise",
+		value: func(data netatmo.DashboardData) *float64 { return intValue(data.Noise) },
+		clear: func(data *netatmo.DashboardData) { data.Noise = nil },
+	},
+	{
+		name:  "pressure",
+		value: func(data netatmo.DashboardData) *float64 { return floatValue(data.Pressure) },
+		clear: func(data *netatmo.DashboardData) { data.Pressure = nil },
+	},
+	{
+		name:  "absolute_pressure",
+		value: func(data netatmo.DashboardData) *float64 { return floatValue(data.AbsolutePressure) },
+		clear: func(data *netatmo.DashboardData) { data.AbsolutePressure = nil },
+	},
+	{
+		name:  "wind_strength",
+		value: func(data netatmo.DashboardData) *float64 { return intValue(data.WindStrength) },
+		clear: func(data *netatmo.DashboardData) { data.WindStrength = nil },
+	},
+	{
+		name:  "rain",
+		value: func(data netatmo.DashboardData) *float64 { return floatValue(data.Rain) },
+		clear: func(data *netatmo.DashboardData) { data.Rain = nil },
+	},
+}
+
+// PlausibilityRule describes the plausible values of a measurement. Values outside of the range are rejected.
+// If MaxJump is not zero, values which differ by more than MaxJump from the last accepted value are rejected as well,
+// until the new level has been confirmed by consecutive readings.
+type PlausibilityRule struct {
+	Min     *float64 `yaml:"min"`
+	Max     *float64 `yaml:"max"`
+	MaxJump float64  `yaml:"max_jump"`
+}
+
+// check returns the reason for rejecting the value or an empty string, if the value is plausible.
+func (r PlausibilityRule) check(value float64, previous *float64) string {
+	switch {
+	case r.Min != nil && value < *r.Min:
+		return rejectReasonTooLow
+	case r.Max != nil && value > *r.Max:
+		return rejectReasonTooHigh
+	case r.MaxJump > 0 && previous != nil && (value-*previous > r.MaxJump || *previous-value > r.MaxJump):
+		return rejectReasonJump
+	default:
+		return ""
+	}
+}
+
+// PlausibilityCheck rejects implausible sensor values. A nil PlausibilityCheck accepts all values.
+type PlausibilityCheck struct {
+	rules map[string]PlausibilityRule
+}
+
+// NewPlausibilityCheck creates a PlausibilityCheck from rules keyed by the measurement name.
+func NewPlausibilityCheck(rules map[string]PlausibilityRule) (*PlausibilityCheck, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !slices.ContainsFunc(measurements, func(m measurement) bool { return m.name == name }) {
+			return nil, fmt.Errorf("unknown measurement %q", name)
+		}
+
+		rule := rules[name]
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return nil, fmt.Errorf("%s: minimum %g is greater than maximum %g", name, *rule.Min, *rule.Max)
+		}
+
+		if rule.MaxJump < 0 {
+			return nil, fmt.Errorf("%s: maximum jump can not be negative", name)
+		}
+	}
+
+	return &PlausibilityCheck{
+		rules: rules,
+	}, nil
+}
+
+// jumpConfirmations is the number of consecutive readings at a new level, which are needed for accepting a jump.
+// Without it, a real change of the measurement by more than the maximum jump would be rejected forever.
+const jumpConfirmations = 3
+
+// plausibilityKey identifies a measurement of a module.
+type plausibilityKey struct {
+	id          string
+	measurement string
+}
+
+// plausibilityState contains the result of the last check of a measurement of a module.
+type plausibilityState struct {
+	// lastMeasure is the time of the last checked reading. Readings with the same time are not checked again.
+	lastMeasure int64
+	// reason is the reason for rejecting the last checked reading, empty if it was accepted.
+	reason string
+	// accepted is true, if value contains the last accepted value.
+	accepted bool
+	value    float64
+	// candidate is the last value rejected as jump and candidates the number of consecutive jumps agreeing with it.
+	candidate  float64
+	candidates int
+}
+
+// rejection identifies the series of the counter of rejected values.
+type rejection struct {
+	id          string
+	module      string
+	station     string
+	home        string
+	measurement string
+	reason      string
+}
+
+// checkPlausibility removes the implausible values from the devices and returns the rejected values.
+// Jumps are detected by comparing with the last accepted value, so that a spike lasting several refreshes is
+// rejected completely. A new level is accepted after jumpConfirmations consecutive readings agreeing with each other.
+// Until a value has been accepted, the previous data is used, for example after restoring the cache.
+// Readings which have already been checked are not counted again. Needs to be called with the cache lock held.
+func (c *NetatmoCollector) checkPlausibility(devices, previous *api.DeviceCollection) []rejection {
+	if c.PlausibilityCheck == nil {
+		return nil
+	}
+
+	previousModules := make(map[string]*api.Device)
+	for _, dev := range previous.Devices() {
+		previousModules[dev.ID] = dev
+		for _, module := range dev.LinkedModules {
+			previousModules[module.ID] = module
+		}
+	}
+
+	if c.plausibility == nil {
+		c.plausibility = make(map[plausibilityKey]*plausibilityState)
+	}
+
+	var rejections []rejection
+	for _, dev := range devices.Devices() {
+		stationName := dev.StationName //nolint: staticcheck
+		for _, module := range append([]*api.Device{dev}, dev.LinkedModules...) {
+			data := c.calibratedData(module)
+
+			var previousData *netatmo.DashboardData
+			if previousModule, ok := previousModules[module.ID]; ok {
+				d := c.calibratedData(previousModule)
+				previousData = &d
+			}
+
+			var lastMeasure int64
+			if data.LastMeasure != nil {
+				lastMeasure = *data.LastMeasure
+			}
+
+			for _, m := range measurements {
+				rule, ok := c.PlausibilityCheck.rules[m.name]
+				if !ok {
+					continue
+				}
+
+				value := m.value(data)
+				if value == nil {
+					continue
+				}
+
+				key := plausibilityKey{
+					id:          module.ID,
+					measurement: m.name,
+				}
+				state, ok := c.plausibility[key]
+				if !ok {
+					state = &plausibilityState{}
+					c.plausibility[key] = state
+				}
+
+				if lastMeasure != 0 && lastMeasure == state.lastMeasure {
+					// NetAtmo has not updated the reading since the last refresh.
+					if state.reason != "" {
+						m.clear(&module.DashboardData)
+					}
+					continue
+				}
+
+				reason := state.check(rule, *value, previousData, m)
+				state.lastMeasure = lastMeasure
+				state.reason = reason
+				if reason == "" {
+					continue
+				}
+
+				c.Log.Debugf("Rejecting %s of %s: %g (%s)", m.name, moduleName(module), *value, reason)
+				m.clear(&module.DashboardData)
+				rejections = append(rejections, rejection{
+					id:          module.ID,
+					module:      moduleName(module),
+					station:     stationName,
+					home:        dev.HomeName,
+					measurement: m.name,
+					reason:      reason,
+				})
+			}
+		}
+	}
+
+	return rejections
+}
+
+// check returns the reason for rejecting the value or an empty string, if the value is accepted.
+func (s *plausibilityState) check(rule PlausibilityRule, value float64, previousData *netatmo.DashboardData, m measurement) string {
+	var previous *float64
+	switch {
+	case s.accepted:
+		previous = &s.value
+	case previousData != nil:
+		previous = m.value(*previousData)
+	}
+
+	reason := rule.check(value, previous)
+	switch reason {
+	case "":
+	case rejectReasonJump:
+		if s.candidates > 0 && math.Abs(value-s.candidate) <= rule.MaxJump {
+			s.candidates++
+		} else {
+			s.candidates = 1
+		}
+		s.candidate = value
+
+		if s.candidates < jumpConfirmations {
+			return reason
+		}
+	default:
+		s.candidates = 0
+		return reason
+	}
+
+	s.accepted = true
+	s.value = value
+	s.candidates = 0
+	return ""
+}
+
+// collectRejected sends the counters of the rejected values. Needs to be called with the cache lock held.
+func (c *NetatmoCollector) collectRejected(ch chan<- prometheus.Metric) {
+	for r, count := range c.rejected {
+		labels := append(c.moduleLabels(r.id, r.module, r.station, r.home), r.measurement, r.reason)
+		c.sendMetric(ch, sensorRejectedDesc, prometheus.CounterValue, count, labels...)
+	}
+}
@@ -0,0 +1,341 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestNewPlausibilityCheck(t *testing.T) {
+	tt := []struct {
+		desc    string
+		rules   map[string]PlausibilityRule
+		wantNil bool
+		wantErr string
+	}{
+		{
+			desc:    "no rules",
+			wantNil: true,
+		},
+		{
+			desc: "valid rules",
+			rules: map[string]PlausibilityRule{
+				"temperature": {Min: float64Ptr(-50), Max: float64Ptr(60), MaxJump: 10},
+				"co2":         {Max: float64Ptr(5000)},
+			},
+		},
+		{
+			desc: "unknown measurement",
+			rules: map[string]PlausibilityRule{
+				"brightness": {Max: float64Ptr(100)},
+			},
+			wantErr: "unknown measurement \"brightness\"",
+		},
+		{
+			desc: "minimum greater than maximum",
+			rules: map[string]PlausibilityRule{
+				"pressure": {Min: float64Ptr(1100), Max: float64Ptr(800)},
+			},
+			wantErr: "pressure: minimum 1100 is greater than maximum 800",
+		},
+		{
+			desc: "negative jump",
+			rules: map[string]PlausibilityRule{
+				"humidity": {MaxJump: -1},
+			},
+			wantErr: "humidity: maximum jump can not be negative",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			check, err := NewPlausibilityCheck(tc.rules)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if (check == nil) != tc.wantNil {
+				t.Errorf("got check %v, want nil %v", check, tc.wantNil)
+			}
+		})
+	}
+}
+
+func TestPlausibilityRule(t *testing.T) {
+	rule := PlausibilityRule{
+		Min:     float64Ptr(-40),
+		Max:     float64Ptr(60),
+		MaxJump: 10,
+	}
+
+	tt := []struct {
+		desc       string
+		value      float64
+		previous   *float64
+		wantReason string
+	}{
+		{
+			desc:  "plausible",
+			value: 21,
+		},
+		{
+			desc:       "too low",
+			value:      -99,
+			wantReason: rejectReasonTooLow,
+		},
+		{
+			desc:       "too high",
+			value:      85,
+			wantReason: rejectReasonTooHigh,
+		},
+		{
+			desc:     "small change",
+			value:    21,
+			previous: float64Ptr(15),
+		},
+		{
+			desc:       "jump up",
+			value:      40,
+			previous:   float64Ptr(21),
+			wantReason: rejectReasonJump,
+		},
+		{
+			desc:       "jump down",
+			value:      -5,
+			previous:   float64Ptr(21),
+			wantReason: rejectReasonJump,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if reason := rule.check(tc.value, tc.previous); reason != tc.wantReason {
+				t.Errorf("got reason %q, want %q", reason, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestNetatmoCollector_CollectPlausibility(t *testing.T) {
+	// The temperature spike lasts for two refreshes and is compared with the last accepted value both times.
+	readings := []netatmo.DashboardData{
+		{
+			Temperature: float32Ptr(21),
+			CO2:         int32Ptr(600),
+			Pressure:    float32Ptr(1012),
+			LastMeasure: int64Ptr(3000),
+		},
+		{
+			Temperature: float32Ptr(35),
+			CO2:         int32Ptr(5500),
+			Pressure:    float32Ptr(0),
+			LastMeasure: int64Ptr(3300),
+		},
+		{
+			Temperature: float32Ptr(36),
+			CO2:         int32Ptr(650),
+			Pressure:    float32Ptr(1011),
+			LastMeasure: int64Ptr(3400),
+		},
+		{
+			Temperature: float32Ptr(22),
+			CO2:         int32Ptr(650),
+			Pressure:    float32Ptr(1011),
+			LastMeasure: int64Ptr(3500),
+		},
+	}
+
+	read := 0
+	readFunction := func() (*api.DeviceCollection, error) {
+		devices := &api.DeviceCollection{}
+		devices.Body.Devices = []*api.Device{
+			{
+				Device: netatmo.Device{
+					ID:            "aa:bb:cc:dd:ee:f0",
+					ModuleName:    "Living Room",
+					HomeName:      "Home",
+					StationName:   "Home (Living Room)",
+					Type:          "NAMain",
+					DashboardData: readings[read],
+				},
+			},
+		}
+		read++
+
+		return devices, nil
+	}
+
+	check, err := NewPlausibilityCheck(map[string]PlausibilityRule{
+		"temperature": {Min: float64Ptr(-40), MaxJump: 10},
+		"co2":         {Max: float64Ptr(5000)},
+		"pressure":    {Min: float64Ptr(800)},
+	})
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	c := New(logrus.New(), readFunction, time.Hour, time.Hour, Names{})
+	c.clock = mockClock
+	c.PlausibilityCheck = check
+	for range readings {
+		c.RefreshData(mockClock())
+	}
+
+	wantMetrics := `# HELP netatmo_sensor_co2_ppm Carbondioxide measurement in parts per million
+# TYPE netatmo_sensor_co2_ppm gauge
+netatmo_sensor_co2_ppm{home="Home",module="Living Room",station="Home (Living Room)"} 650
+# HELP netatmo_sensor_pressure_mb Atmospheric pressure measurement in millibar
+# TYPE netatmo_sensor_pressure_mb gauge
+netatmo_sensor_pressure_mb{home="Home",module="Living Room",station="Home (Living Room)"} 1011
+# HELP netatmo_sensor_rejected_total Number of sensor values rejected by the plausibility checks.
+# TYPE netatmo_sensor_rejected_total counter
+netatmo_sensor_rejected_total{home="Home",measurement="co2",module="Living Room",reason="too_high",station="Home (Living Room)"} 1
+netatmo_sensor_rejected_total{home="Home",measurement="pressure",module="Living Room",reason="too_low",station="Home (Living Room)"} 1
+netatmo_sensor_rejected_total{home="Home",measurement="temperature",module="Living Room",reason="jump",station="Home (Living Room)"} 2
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} 22
+`
+	metricNames := []string{
+		"netatmo_sensor_co2_ppm",
+		"netatmo_sensor_pressure_mb",
+		"netatmo_sensor_rejected_total",
+		"netatmo_sensor_temperature_celsius",
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNetatmoCollector_PlausibilityJumps(t *testing.T) {
+	type reading struct {
+		temperature float32
+		lastMeasure int64
+	}
+
+	tt := []struct {
+		desc            string
+		readings        []reading
+		wantTemperature string
+		wantRejected    string
+	}{
+		{
+			desc: "level change confirmed",
+			readings: []reading{
+				{temperature: 21, lastMeasure: 3000},
+				{temperature: 35, lastMeasure: 3100},
+				{temperature: 35.5, lastMeasure: 3200},
+				{temperature: 36, lastMeasure: 3300},
+			},
+			wantTemperature: "36",
+			wantRejected:    "2",
+		},
+		{
+			desc: "disagreeing jumps",
+			readings: []reading{
+				{temperature: 21, lastMeasure: 3000},
+				{temperature: 35, lastMeasure: 3100},
+				{temperature: 5, lastMeasure: 3200},
+				{temperature: 36, lastMeasure: 3300},
+			},
+			wantRejected: "3",
+		},
+		{
+			desc: "unchanged reading counted once",
+			readings: []reading{
+				{temperature: 21, lastMeasure: 3000},
+				{temperature: 35, lastMeasure: 3100},
+				{temperature: 35, lastMeasure: 3100},
+				{temperature: 35, lastMeasure: 3100},
+			},
+			wantRejected: "1",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			read := 0
+			readFunction := func() (*api.DeviceCollection, error) {
+				devices := &api.DeviceCollection{}
+				devices.Body.Devices = []*api.Device{
+					{
+						Device: netatmo.Device{
+							ID:          "aa:bb:cc:dd:ee:f0",
+							ModuleName:  "Living Room",
+							HomeName:    "Home",
+							StationName: "Home (Living Room)",
+							Type:        "NAMain",
+							DashboardData: netatmo.DashboardData{
+								Temperature: float32Ptr(tc.readings[read].temperature),
+								LastMeasure: int64Ptr(tc.readings[read].lastMeasure),
+							},
+						},
+					},
+				}
+				read++
+
+				return devices, nil
+			}
+
+			check, err := NewPlausibilityCheck(map[string]PlausibilityRule{
+				"temperature": {MaxJump: 10},
+			})
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			mockClock := func() time.Time {
+				return time.Unix(3600, 0)
+			}
+			c := New(logrus.New(), readFunction, time.Hour, time.Hour, Names{})
+			c.clock = mockClock
+			c.PlausibilityCheck = check
+			for range tc.readings {
+				c.RefreshData(mockClock())
+			}
+
+			wantMetrics := `# HELP netatmo_sensor_rejected_total Number of sensor values rejected by the plausibility checks.
+# TYPE netatmo_sensor_rejected_total counter
+netatmo_sensor_rejected_total{home="Home",measurement="temperature",module="Living Room",reason="jump",station="Home (Living Room)"} ` + tc.wantRejected + `
+`
+			if tc.wantTemperature != "" {
+				wantMetrics += `# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{home="Home",module="Living Room",station="Home (Living Room)"} ` + tc.wantTemperature + `
+`
+			}
+			metricNames := []string{
+				"netatmo_sensor_rejected_total",
+				"netatmo_sensor_temperature_celsius",
+			}
+			if err := testutil.CollectAndCompare(c, strings.NewReader(wantMetrics), metricNames...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"slices"
+	"time"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+// measurementBattery is the name of the battery level in readings. It is not a sensor value, so it can not be calibrated.
+const measurementBattery = "battery"
+
+// Reading is a single value of a module after calibration and plausibility checks.
+type Reading struct {
+	Module      *api.Device
+	ModuleName  string
+	StationName string
+	HomeName    string
+	Measurement string
+	Value       float64
+	Time        time.Time
+}
+
+// KnownMeasurement returns true, if readings can contain the measurement.
+func KnownMeasurement(name string) bool {
+	return name == measurementBattery || slices.ContainsFunc(measurements, func(m measurement) bool {
+		return m.name == name
+	})
+}
+
+// readings returns the current values of all modules which are not hidden by the module filter.
+func (c *NetatmoCollector) readings(devices *api.DeviceCollection) []Reading {
+	var result []Reading
+	for _, dev := range devices.Devices() {
+		homeName := dev.HomeName
+		stationName := dev.StationName //nolint: staticcheck
+		for _, module := range append([]*api.Device{dev}, dev.LinkedModules...) {
+			if !c.hasCurrentData(module) || !c.ModuleFilter.Match(module, stationName, homeName) {
+				continue
+			}
+
+			reading := Reading{
+				Module:      module,
+				ModuleName:  moduleName(module),
+				StationName: stationName,
+				HomeName:    homeName,
+				Time:        time.Unix(*module.DashboardData.LastMeasure, 0),
+			}
+
+			data := c.calibratedData(module)
+			for _, m := range measurements {
+				if value := m.value(data); value != nil {
+					reading.Measurement = m.name
+					reading.Value = *value
+					result = append(result, reading)
+				}
+			}
+
+			if module.BatteryPercent != nil {
+				reading.Measurement = measurementBattery
+				reading.Value = float64(*module.BatteryPercent)
+				result = append(result, reading)
+			}
+		}
+	}
+
+	return result
+}
+
+// Readings returns the readings of the cached data. The data is only used, if the cache policy allows serving it.
+// Like a scrape, this starts a refresh in the background when the data is older than the refresh interval.
+func (c *NetatmoCollector) Readings() []Reading {
+	now := c.clock()
+	if c.claimRefresh(now) {
+		go c.refresh(now)
+	}
+
+	c.cacheLock.RLock()
+	defer c.cacheLock.RUnlock()
+
+	if serve, _ := c.serveCache(now); !serve {
+		return nil
+	}
+
+	return c.readings(c.cachedData)
+}
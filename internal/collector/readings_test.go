@@ -0,0 +1,316 @@
+package collector
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+)
+
+func TestNetatmoCollector_OnRefresh(t *testing.T) {
+	readFunction := func() (*api.DeviceCollection, error) {
+		devices := &api.DeviceCollection{}
+		devices.Body.Devices = []*api.Device{
+			{
+				Device: netatmo.Device{
+					ID:          "aa:bb:cc:dd:ee:f0",
+					ModuleName:  "Living Room",
+					HomeName:    "Home",
+					StationName: "Home (Living Room)",
+					Type:        "NAMain",
+					DashboardData: netatmo.DashboardData{
+						Temperature: float32Ptr(21.5),
+						CO2:         int32Ptr(600),
+						LastMeasure: int64Ptr(3000),
+					},
+				},
+				LinkedModules: []*api.Device{
+					{
+						Device: netatmo.Device{
+							ID:             "aa:bb:cc:dd:ee:f1",
+							ModuleName:     "Outside",
+							Type:           "NAModule1",
+							BatteryPercent: int32Ptr(80),
+							DashboardData: netatmo.DashboardData{
+								Humidity:    int32Ptr(60),
+								LastMeasure: int64Ptr(3000),
+							},
+						},
+					},
+					{
+						Device: netatmo.Device{
+							ID:         "aa:bb:cc:dd:ee:f2",
+							ModuleName: "Stale",
+							Type:       "NAModule4",
+							DashboardData: netatmo.DashboardData{
+								CO2:         int32Ptr(1000),
+								LastMeasure: int64Ptr(0),
+							},
+						},
+					},
+				},
+			},
+		}
+
+		return devices, nil
+	}
+
+	mockClock := func() time.Time {
+		return time.Unix(3600, 0)
+	}
+	c := New(logrus.New(), readFunction, time.Hour, 30*time.Minute, Names{})
+	c.clock = mockClock
+	c.SetCalibration(map[string]ModuleCalibration{
+		"AA:BB:CC:DD:EE:F0": {Temperature: &Calibration{Offset: -0.5}},
+	})
+
+	var got []Reading
+	c.OnRefresh = append(c.OnRefresh, func(_ time.Time, readings []Reading) {
+		got = readings
+	})
+	c.RefreshData(mockClock())
+
+	type reading struct {
+		module      string
+		measurement string
+		value       float64
+	}
+	want := []reading{
+		{module: "Living Room", measurement: "temperature", value: 21},
+		{module: "Living Room", measurement: "co2", value: 600},
+		{module: "Outside", measurement: "humidity", value: 60},
+		{module: "Outside", measurement: measurementBattery, value: 80},
+	}
+
+	var result []reading
+	for _, r := range got {
+		result = append(result, reading{
+			module:      r.ModuleName,
+			measurement: r.Measurement,
+			value:       r.Value,
+		})
+	}
+
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got readings %v, want %v", result, want)
+	}
+}
+
+func TestNetatmoCollector_RefreshLoop(t *testing.T) {
+	refreshed := make(chan time.Time, 10)
+	readFunction := func() (*api.DeviceCollection, error) {
+		return &api.DeviceCollection{}, nil
+	}
+
+	c := New(logrus.New(), readFunction, 10*time.Millisecond, time.Hour, Names{})
+	c.OnRefresh = append(c.OnRefresh, func(now time.Time, _ []Reading) {
+		refreshed <- now
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.RefreshLoop(ctx)
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-refreshed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for refresh %d", i+1)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresh loop did not stop")
+	}
+}
+
+func TestNetatmoCollector_Readings(t *testing.T) {
+	readFunction := func() (*api.DeviceCollection, error) {
+		devices := &api.DeviceCollection{}
+		devices.Body.Devices = []*api.Device{
+			{
+				Device: netatmo.Device{
+					ID:         "aa:bb:cc:dd:ee:f0",
+					ModuleName: "Living Room",
+					Type:       "NAMain",
+					DashboardData: netatmo.DashboardData{
+						Temperature: float32Ptr(21),
+						LastMeasure: int64Ptr(3000),
+					},
+				},
+			},
+		}
+
+		return devices, nil
+	}
+
+	now := time.Unix(3600, 0)
+	c := New(logrus.New(), readFunction, 24*time.Hour, 24*time.Hour, Names{})
+	c.clock = func() time.Time {
+		return now
+	}
+
+	c.RefreshData(now)
+	got := c.Readings()
+	if len(got) != 1 || got[0].Measurement != "temperature" || got[0].Value != 21 {
+		t.Errorf("got readings %v, want temperature of 21", got)
+	}
+
+	// Data older than the maximum age is not served with the "serve-stale" policy.
+	c.CachePolicy = CachePolicyServeStale
+	c.CacheMaxAge = time.Minute
+	now = now.Add(time.Hour)
+	if got := c.Readings(); len(got) != 0 {
+		t.Errorf("got %d readings from expired cache, want none", len(got))
+	}
+}
+
+func TestNetatmoCollector_RefreshLoopWaitsForBudget(t *testing.T) {
+	refreshed := make(chan time.Time, 10)
+	readFunction := func() (*api.DeviceCollection, error) {
+		return &api.DeviceCollection{}, nil
+	}
+
+	budget := make(chan struct{})
+	c := New(logrus.New(), readFunction, time.Hour, time.Hour, Names{})
+	c.WaitFunction = func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-budget:
+			return nil
+		}
+	}
+	c.OnRefresh = append(c.OnRefresh, func(now time.Time, _ []Reading) {
+		refreshed <- now
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.RefreshLoop(ctx)
+
+	select {
+	case <-refreshed:
+		t.Fatal("refreshed before budget was available")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(budget)
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for refresh")
+	}
+}
+
+func TestNetatmoCollector_RefreshDelay(t *testing.T) {
+	readFunction := func() (*api.DeviceCollection, error) {
+		return &api.DeviceCollection{}, nil
+	}
+
+	c := New(logrus.New(), readFunction, 8*time.Minute, time.Hour, Names{})
+	c.RefreshData(time.Unix(0, 0))
+
+	// A scrape refreshes the data between two refreshes of the loop.
+	c.RefreshData(time.Unix(180, 0))
+
+	tt := []struct {
+		desc      string
+		now       time.Time
+		wantDelay time.Duration
+	}{
+		{
+			desc:      "right after refresh",
+			now:       time.Unix(180, 0),
+			wantDelay: 8 * time.Minute,
+		},
+		{
+			desc:      "scheduled from last refresh",
+			now:       time.Unix(480, 0),
+			wantDelay: 3 * time.Minute,
+		},
+		{
+			desc:      "overdue",
+			now:       time.Unix(900, 0),
+			wantDelay: -4 * time.Minute,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if delay := c.refreshDelay(tc.now); delay != tc.wantDelay {
+				t.Errorf("got delay %s, want %s", delay, tc.wantDelay)
+			}
+
+			if due := c.refreshDue(tc.now); due != (tc.wantDelay <= 0) {
+				t.Errorf("got due %v, want %v", due, tc.wantDelay <= 0)
+			}
+		})
+	}
+}
+
+func TestNetatmoCollector_RefreshInFlight(t *testing.T) {
+	var lock sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	readFunction := func() (*api.DeviceCollection, error) {
+		lock.Lock()
+		calls++
+		lock.Unlock()
+
+		<-release
+		return &api.DeviceCollection{}, nil
+	}
+
+	now := time.Unix(0, 0)
+	c := New(logrus.New(), readFunction, 8*time.Minute, time.Hour, Names{})
+	c.clock = func() time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return now
+	}
+
+	c.Readings()
+	c.Readings()
+
+	// The refresh takes longer than the refresh interval.
+	lock.Lock()
+	now = now.Add(10 * time.Minute)
+	lock.Unlock()
+	c.Readings()
+
+	if c.claimRefresh(c.clock()) {
+		t.Error("got refresh claimed, want it to be in flight")
+	}
+
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.claimRefresh(c.clock()) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for refresh to finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if calls != 1 {
+		t.Errorf("got %d refreshes, want 1", calls)
+	}
+}
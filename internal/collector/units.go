@@ -0,0 +1,21 @@
+package collector
+
+// UnitSystem selects the units sensor values are exported in.
+type UnitSystem string
+
+const (
+	// UnitSystemMetric only exports the metric units provided by NetAtmo.
+	UnitSystemMetric UnitSystem = "metric"
+	// UnitSystemImperial exports imperial units in addition to the metric units.
+	UnitSystemImperial UnitSystem = "imperial"
+)
+
+// Valid returns true if the unit system is known.
+func (u UnitSystem) Valid() bool {
+	switch u {
+	case UnitSystemMetric, UnitSystemImperial:
+		return true
+	default:
+		return false
+	}
+}
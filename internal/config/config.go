@@ -4,11 +4,21 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/exzz/netatmo-api-go"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+	"github.com/xperimental/netatmo-exporter/v2/internal/influx"
+	"github.com/xperimental/netatmo-exporter/v2/internal/mqtt"
+	"github.com/xperimental/netatmo-exporter/v2/internal/remotewrite"
+	"github.com/xperimental/netatmo-exporter/v2/internal/signal"
 )
 
 const (
@@ -21,6 +31,46 @@ const (
 	envVarStaleDuration       = "NETATMO_AGE_STALE"
 	envVarNetatmoClientID     = "NETATMO_CLIENT_ID"
 	envVarNetatmoClientSecret = "NETATMO_CLIENT_SECRET"
+	envVarWifiThresholds      = "NETATMO_WIFI_THRESHOLDS"
+	envVarRFThresholds        = "NETATMO_RF_THRESHOLDS"
+	envVarDerivedMetrics      = "NETATMO_DERIVED_METRICS"
+	envVarUnitSystem          = "NETATMO_UNIT_SYSTEM"
+	envVarMetricNaming        = "NETATMO_METRICS_NAMING"
+	envVarMetricTimestamps    = "NETATMO_METRICS_TIMESTAMPS"
+	envVarMetricPrefix        = "NETATMO_METRICS_PREFIX"
+	envVarLabelNames          = "NETATMO_METRICS_LABEL_NAMES"
+	envVarGoCollector         = "NETATMO_METRICS_GO"
+	envVarProcessCollector    = "NETATMO_METRICS_PROCESS"
+	envVarBuildInfoCollector  = "NETATMO_METRICS_BUILD_INFO"
+	envVarAPILimits           = "NETATMO_API_LIMITS"
+	envVarRefreshHistory      = "NETATMO_REFRESH_HISTORY"
+	envVarCachePolicy         = "NETATMO_CACHE_POLICY"
+	envVarCacheMaxAge         = "NETATMO_CACHE_MAX_AGE"
+	envVarCachePersist        = "NETATMO_CACHE_PERSIST"
+	envVarConfigFile          = "NETATMO_CONFIG_FILE"
+	envVarMQTTBroker          = "NETATMO_MQTT_BROKER"
+	envVarMQTTClientID        = "NETATMO_MQTT_CLIENT_ID"
+	envVarMQTTUsername        = "NETATMO_MQTT_USERNAME"
+	envVarMQTTPassword        = "NETATMO_MQTT_PASSWORD"
+	envVarMQTTTopicPrefix     = "NETATMO_MQTT_TOPIC_PREFIX"
+	envVarMQTTQoS             = "NETATMO_MQTT_QOS"
+	envVarMQTTDiscovery       = "NETATMO_MQTT_DISCOVERY"
+	envVarMQTTDiscoveryPrefix = "NETATMO_MQTT_DISCOVERY_PREFIX"
+	envVarMQTTCAFile          = "NETATMO_MQTT_TLS_CA_FILE"
+	envVarMQTTCertFile        = "NETATMO_MQTT_TLS_CERT_FILE"
+	envVarMQTTKeyFile         = "NETATMO_MQTT_TLS_KEY_FILE"
+	envVarMQTTInsecure        = "NETATMO_MQTT_TLS_INSECURE_SKIP_VERIFY"
+	envVarRemoteWriteURL      = "NETATMO_REMOTE_WRITE_URL"
+	envVarRemoteWriteUsername = "NETATMO_REMOTE_WRITE_USERNAME"
+	envVarRemoteWritePassword = "NETATMO_REMOTE_WRITE_PASSWORD"
+	envVarRemoteWriteToken    = "NETATMO_REMOTE_WRITE_BEARER_TOKEN"
+	envVarRemoteWriteLabels   = "NETATMO_REMOTE_WRITE_EXTERNAL_LABELS"
+	envVarRemoteWriteQueue    = "NETATMO_REMOTE_WRITE_QUEUE_SIZE"
+	envVarInfluxHandler       = "NETATMO_INFLUX_HANDLER"
+	envVarInfluxURL           = "NETATMO_INFLUX_URL"
+	envVarInfluxOrg           = "NETATMO_INFLUX_ORG"
+	envVarInfluxBucket        = "NETATMO_INFLUX_BUCKET"
+	envVarInfluxToken         = "NETATMO_INFLUX_TOKEN"
 
 	flagListenAddress       = "addr"
 	flagExternalURL         = "external-url"
@@ -31,17 +81,80 @@ const (
 	flagStaleDuration       = "age-stale"
 	flagNetatmoClientID     = "client-id"
 	flagNetatmoClientSecret = "client-secret"
+	flagWifiThresholds      = "wifi-thresholds"
+	flagRFThresholds        = "rf-thresholds"
+	flagDerivedMetrics      = "derived-metrics"
+	flagUnitSystem          = "unit-system"
+	flagMetricNaming        = "metrics.naming"
+	flagMetricTimestamps    = "metrics.timestamps"
+	flagMetricPrefix        = "metrics.prefix"
+	flagLabelNames          = "metrics.label-names"
+	flagGoCollector         = "metrics.go"
+	flagProcessCollector    = "metrics.process"
+	flagBuildInfoCollector  = "metrics.build-info"
+	flagAPILimits           = "api.limits"
+	flagRefreshHistory      = "refresh-history"
+	flagCachePolicy         = "cache.policy"
+	flagCacheMaxAge         = "cache.max-age"
+	flagCachePersist        = "cache.persist"
+	flagConfigFile          = "config-file"
+	flagMQTTBroker          = "mqtt.broker"
+	flagMQTTClientID        = "mqtt.client-id"
+	flagMQTTUsername        = "mqtt.username"
+	flagMQTTPassword        = "mqtt.password"
+	flagMQTTTopicPrefix     = "mqtt.topic-prefix"
+	flagMQTTQoS             = "mqtt.qos"
+	flagMQTTDiscovery       = "mqtt.discovery"
+	flagMQTTDiscoveryPrefix = "mqtt.discovery-prefix"
+	flagMQTTCAFile          = "mqtt.tls.ca-file"
+	flagMQTTCertFile        = "mqtt.tls.cert-file"
+	flagMQTTKeyFile         = "mqtt.tls.key-file"
+	flagMQTTInsecure        = "mqtt.tls.insecure-skip-verify"
+	flagRemoteWriteURL      = "remote-write.url"
+	flagRemoteWriteUsername = "remote-write.username"
+	flagRemoteWritePassword = "remote-write.password"
+	flagRemoteWriteToken    = "remote-write.bearer-token"
+	flagRemoteWriteLabels   = "remote-write.external-labels"
+	flagRemoteWriteQueue    = "remote-write.queue-size"
+	flagInfluxHandler       = "influx.handler"
+	flagInfluxURL           = "influx.url"
+	flagInfluxOrg           = "influx.org"
+	flagInfluxBucket        = "influx.bucket"
+	flagInfluxToken         = "influx.token"
 
 	defaultRefreshInterval = 8 * time.Minute
 	defaultStaleDuration   = 60 * time.Minute
+	defaultCacheMaxAge     = 60 * time.Minute
+
+	cacheFileName = "netatmo-cache.json"
 )
 
 var (
 	defaultConfig = Config{
-		Addr:            ":9210",
-		LogLevel:        logLevel(logrus.InfoLevel),
-		RefreshInterval: defaultRefreshInterval,
-		StaleDuration:   defaultStaleDuration,
+		Addr:               ":9210",
+		LogLevel:           logLevel(logrus.InfoLevel),
+		RefreshInterval:    defaultRefreshInterval,
+		StaleDuration:      defaultStaleDuration,
+		WifiThresholds:     signal.DefaultWifiThresholds,
+		RFThresholds:       signal.DefaultRFThresholds,
+		UnitSystem:         collector.UnitSystemMetric,
+		MetricNaming:       collector.MetricNamingV1,
+		MetricPrefix:       collector.DefaultPrefix,
+		GoCollector:        true,
+		ProcessCollector:   true,
+		BuildInfoCollector: true,
+		APILimits:          api.DefaultLimits,
+		RefreshHistory:     collector.DefaultRefreshHistorySize,
+		CachePolicy:        collector.CachePolicyServeForever,
+		CacheMaxAge:        defaultCacheMaxAge,
+		MQTT: mqtt.Config{
+			ClientID:        mqtt.DefaultClientID,
+			TopicPrefix:     mqtt.DefaultTopicPrefix,
+			DiscoveryPrefix: mqtt.DefaultDiscoveryPrefix,
+		},
+		RemoteWrite: remotewrite.Config{
+			QueueSize: remotewrite.DefaultQueueSize,
+		},
 	}
 
 	errNoBinaryName          = errors.New("need the binary name as first argument")
@@ -49,6 +162,13 @@ var (
 	errNoTokenFile           = errors.New("need a token file to save the token")
 	errNoNetatmoClientID     = errors.New("need a NetAtmo client ID")
 	errNoNetatmoClientSecret = errors.New("need a NetAtmo client secret")
+	errInvalidWifiThresholds = fmt.Errorf("need %d descending Wi-Fi signal thresholds", len(signal.DefaultWifiThresholds))
+	errInvalidRFThresholds   = fmt.Errorf("need %d descending RF signal thresholds", len(signal.DefaultRFThresholds))
+	errInvalidUnitSystem     = fmt.Errorf("unit system needs to be %q or %q", collector.UnitSystemMetric, collector.UnitSystemImperial)
+	errInvalidRefreshHistory = errors.New("refresh history can not be negative")
+	errInvalidCachePolicy    = fmt.Errorf("cache policy needs to be %q, %q or %q", collector.CachePolicyServeForever, collector.CachePolicyServeStale, collector.CachePolicyDrop)
+	errInvalidCacheMaxAge    = errors.New("cache max age needs to be positive")
+	errInvalidMetricNaming   = fmt.Errorf("metric naming needs to be %q, %q or %q", collector.MetricNamingV1, collector.MetricNamingV2, collector.MetricNamingDual)
 )
 
 type logLevel logrus.Level
@@ -73,14 +193,36 @@ func (l *logLevel) Set(value string) error {
 
 // Config contains the configuration options.
 type Config struct {
-	Addr            string
-	ExternalURL     string
-	TokenFile       string
-	DebugHandlers   bool
-	LogLevel        logLevel
-	RefreshInterval time.Duration
-	StaleDuration   time.Duration
-	Netatmo         netatmo.Config
+	Addr               string
+	ExternalURL        string
+	TokenFile          string
+	DebugHandlers      bool
+	LogLevel           logLevel
+	RefreshInterval    time.Duration
+	StaleDuration      time.Duration
+	WifiThresholds     []int
+	RFThresholds       []int
+	DerivedMetrics     bool
+	UnitSystem         collector.UnitSystem
+	MetricNaming       collector.MetricNaming
+	MetricTimestamps   bool
+	MetricPrefix       string
+	LabelNames         map[string]string
+	GoCollector        bool
+	ProcessCollector   bool
+	BuildInfoCollector bool
+	APILimits          api.Limits
+	RefreshHistory     int
+	CachePolicy        collector.CachePolicy
+	CacheMaxAge        time.Duration
+	CachePersist       bool
+	CacheFile          string
+	ConfigFile         string
+	File               File
+	MQTT               mqtt.Config
+	RemoteWrite        remotewrite.Config
+	Influx             influx.Config
+	Netatmo            netatmo.Config
 }
 
 // Parse takes the arguments and environment variables provided and creates the Config from that.
@@ -94,11 +236,51 @@ func Parse(args []string, getEnv func(string) string) (Config, error) {
 	flagSet := pflag.NewFlagSet(args[0], pflag.ContinueOnError)
 	flagSet.StringVarP(&cfg.Addr, flagListenAddress, "a", cfg.Addr, "Address to listen on.")
 	flagSet.StringVar(&cfg.ExternalURL, flagExternalURL, cfg.ExternalURL, "External URL to use as base for OAuth redirect URL.")
+	flagSet.StringVarP(&cfg.ConfigFile, flagConfigFile, "c", cfg.ConfigFile, "Path to YAML configuration file with settings for individual modules.")
 	flagSet.StringVar(&cfg.TokenFile, flagTokenFile, cfg.TokenFile, "Path to token file for loading/persisting authentication token.")
 	flagSet.BoolVar(&cfg.DebugHandlers, flagDebugHandlers, cfg.DebugHandlers, "Enables debugging HTTP handlers.")
 	flagSet.Var(&cfg.LogLevel, flagLogLevel, "Sets the minimum level output through logging.")
 	flagSet.DurationVar(&cfg.RefreshInterval, flagRefreshInterval, cfg.RefreshInterval, "Time interval used for internal caching of NetAtmo sensor data.")
 	flagSet.DurationVar(&cfg.StaleDuration, flagStaleDuration, cfg.StaleDuration, "Data age to consider as stale. Stale data does not create metrics anymore.")
+	flagSet.IntSliceVar(&cfg.WifiThresholds, flagWifiThresholds, cfg.WifiThresholds, "Wi-Fi signal strength thresholds for the \"bad\" and \"average\" quality levels.")
+	flagSet.IntSliceVar(&cfg.RFThresholds, flagRFThresholds, cfg.RFThresholds, "RF signal strength thresholds for the \"low\", \"medium\" and \"high\" quality levels.")
+	flagSet.BoolVar(&cfg.DerivedMetrics, flagDerivedMetrics, cfg.DerivedMetrics, "Enables metrics derived from the sensor values, like dew point or wind chill.")
+	flagSet.StringVar((*string)(&cfg.UnitSystem), flagUnitSystem, string(cfg.UnitSystem), "Unit system for sensor values. \"imperial\" exports imperial units in addition to metric ones.")
+	flagSet.StringVar((*string)(&cfg.MetricNaming), flagMetricNaming, string(cfg.MetricNaming), "Naming scheme for metrics. \"v2\" uses base units, \"dual\" exports both v1 and v2 names.")
+	flagSet.BoolVar(&cfg.MetricTimestamps, flagMetricTimestamps, cfg.MetricTimestamps, "Enables the OpenMetrics format and uses the time of the measurement as timestamp for sensor metrics.")
+	flagSet.StringVar(&cfg.MetricPrefix, flagMetricPrefix, cfg.MetricPrefix, "Prefix for the names of the sensor metrics.")
+	flagSet.StringToStringVar(&cfg.LabelNames, flagLabelNames, cfg.LabelNames, "Renames labels of the sensor metrics, for example \"station=site,module=sensor\".")
+	flagSet.BoolVar(&cfg.GoCollector, flagGoCollector, cfg.GoCollector, "Includes metrics about the Go runtime in the exporter metrics.")
+	flagSet.BoolVar(&cfg.ProcessCollector, flagProcessCollector, cfg.ProcessCollector, "Includes metrics about the exporter process in the exporter metrics.")
+	flagSet.BoolVar(&cfg.BuildInfoCollector, flagBuildInfoCollector, cfg.BuildInfoCollector, "Includes the Go build information in the exporter metrics.")
+	flagSet.Var(&cfg.APILimits, flagAPILimits, "Rate limits for requests to the NetAtmo API as comma-separated list of requests/window. \"none\" disables the limits.")
+	flagSet.IntVar(&cfg.RefreshHistory, flagRefreshHistory, cfg.RefreshHistory, "Number of refresh attempts kept for debugging. Zero disables the history.")
+	flagSet.StringVar((*string)(&cfg.CachePolicy), flagCachePolicy, string(cfg.CachePolicy), "Policy for serving cached data when refreshing fails. One of \"serve-forever\", \"serve-stale\" or \"drop\".")
+	flagSet.DurationVar(&cfg.CacheMaxAge, flagCacheMaxAge, cfg.CacheMaxAge, "Maximum age of cached data used by the \"serve-stale\" and \"drop\" cache policies.")
+	flagSet.BoolVar(&cfg.CachePersist, flagCachePersist, cfg.CachePersist, "Persists the cached data in a file next to the token file, so that it survives restarts.")
+	flagSet.StringVar(&cfg.MQTT.Broker, flagMQTTBroker, cfg.MQTT.Broker, "URL of MQTT broker for publishing the sensor readings, for example \"tcp://localhost:1883\". Empty disables MQTT.")
+	flagSet.StringVar(&cfg.MQTT.ClientID, flagMQTTClientID, cfg.MQTT.ClientID, "Client ID used for connecting to the MQTT broker.")
+	flagSet.StringVar(&cfg.MQTT.Username, flagMQTTUsername, cfg.MQTT.Username, "Username for the MQTT broker.")
+	flagSet.StringVar(&cfg.MQTT.Password, flagMQTTPassword, cfg.MQTT.Password, "Password for the MQTT broker.")
+	flagSet.StringVar(&cfg.MQTT.TopicPrefix, flagMQTTTopicPrefix, cfg.MQTT.TopicPrefix, "Prefix of the MQTT topics the readings are published to.")
+	flagSet.IntVar(&cfg.MQTT.QoS, flagMQTTQoS, cfg.MQTT.QoS, "Quality of service level of the published MQTT messages.")
+	flagSet.BoolVar(&cfg.MQTT.Discovery, flagMQTTDiscovery, cfg.MQTT.Discovery, "Publishes Home Assistant MQTT discovery messages for the sensors.")
+	flagSet.StringVar(&cfg.MQTT.DiscoveryPrefix, flagMQTTDiscoveryPrefix, cfg.MQTT.DiscoveryPrefix, "Topic prefix of the Home Assistant MQTT discovery messages.")
+	flagSet.StringVar(&cfg.MQTT.TLS.CAFile, flagMQTTCAFile, cfg.MQTT.TLS.CAFile, "Path to CA certificates for verifying the MQTT broker.")
+	flagSet.StringVar(&cfg.MQTT.TLS.CertFile, flagMQTTCertFile, cfg.MQTT.TLS.CertFile, "Path to client certificate for the MQTT broker.")
+	flagSet.StringVar(&cfg.MQTT.TLS.KeyFile, flagMQTTKeyFile, cfg.MQTT.TLS.KeyFile, "Path to key of the client certificate for the MQTT broker.")
+	flagSet.BoolVar(&cfg.MQTT.TLS.InsecureSkipVerify, flagMQTTInsecure, cfg.MQTT.TLS.InsecureSkipVerify, "Disables the verification of the MQTT broker certificate.")
+	flagSet.StringVar(&cfg.RemoteWrite.URL, flagRemoteWriteURL, cfg.RemoteWrite.URL, "URL of Prometheus remote write endpoint the sensor metrics are pushed to after each refresh. Empty disables pushing.")
+	flagSet.StringVar(&cfg.RemoteWrite.Username, flagRemoteWriteUsername, cfg.RemoteWrite.Username, "Username for basic authentication at the remote write endpoint.")
+	flagSet.StringVar(&cfg.RemoteWrite.Password, flagRemoteWritePassword, cfg.RemoteWrite.Password, "Password for basic authentication at the remote write endpoint.")
+	flagSet.StringVar(&cfg.RemoteWrite.BearerToken, flagRemoteWriteToken, cfg.RemoteWrite.BearerToken, "Bearer token for the remote write endpoint.")
+	flagSet.StringToStringVar(&cfg.RemoteWrite.ExternalLabels, flagRemoteWriteLabels, cfg.RemoteWrite.ExternalLabels, "Labels added to all pushed series, for example \"site=home\".")
+	flagSet.IntVar(&cfg.RemoteWrite.QueueSize, flagRemoteWriteQueue, cfg.RemoteWrite.QueueSize, "Number of remote write requests kept for retrying, when the endpoint is not reachable.")
+	flagSet.BoolVar(&cfg.Influx.Handler, flagInfluxHandler, cfg.Influx.Handler, "Enables the /influx endpoint serving the readings in InfluxDB line protocol.")
+	flagSet.StringVar(&cfg.Influx.URL, flagInfluxURL, cfg.Influx.URL, "URL of InfluxDB server the readings are written to after each refresh. Empty disables writing.")
+	flagSet.StringVar(&cfg.Influx.Org, flagInfluxOrg, cfg.Influx.Org, "InfluxDB organization the bucket belongs to.")
+	flagSet.StringVar(&cfg.Influx.Bucket, flagInfluxBucket, cfg.Influx.Bucket, "InfluxDB bucket the readings are written to.")
+	flagSet.StringVar(&cfg.Influx.Token, flagInfluxToken, cfg.Influx.Token, "API token for writing to InfluxDB.")
 	flagSet.StringVarP(&cfg.Netatmo.ClientID, flagNetatmoClientID, "i", cfg.Netatmo.ClientID, "Client ID for NetAtmo app.")
 	flagSet.StringVarP(&cfg.Netatmo.ClientSecret, flagNetatmoClientSecret, "s", cfg.Netatmo.ClientSecret, "Client secret for NetAtmo app.")
 
@@ -114,6 +296,39 @@ func Parse(args []string, getEnv func(string) string) (Config, error) {
 		return Config{}, errNoListenAddress
 	}
 
+	metricNames := collector.Names{
+		Prefix: cfg.MetricPrefix,
+		Labels: cfg.LabelNames,
+	}
+	if err := metricNames.Validate(); err != nil {
+		return Config{}, fmt.Errorf("error in metric names: %w", err)
+	}
+
+	if cfg.ConfigFile != "" {
+		file, err := loadFile(cfg.ConfigFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("error in configuration file: %w", err)
+		}
+
+		if _, err := collector.NewModuleFilter(file.Modules.Include, file.Modules.Exclude); err != nil {
+			return Config{}, fmt.Errorf("error in module filters: %w", err)
+		}
+
+		if err := collector.ValidateModuleLabels(file.Modules.Labels, metricNames); err != nil {
+			return Config{}, fmt.Errorf("error in module labels: %w", err)
+		}
+
+		if _, err := collector.NewPlausibilityCheck(file.Plausibility); err != nil {
+			return Config{}, fmt.Errorf("error in plausibility rules: %w", err)
+		}
+
+		if err := file.Alerts.Validate(); err != nil {
+			return Config{}, fmt.Errorf("error in alerts: %w", err)
+		}
+
+		cfg.File = file
+	}
+
 	if cfg.ExternalURL == "" {
 		host, port, err := net.SplitHostPort(cfg.Addr)
 		if err != nil {
@@ -131,6 +346,10 @@ func Parse(args []string, getEnv func(string) string) (Config, error) {
 		return Config{}, errNoTokenFile
 	}
 
+	if cfg.CachePersist {
+		cfg.CacheFile = filepath.Join(filepath.Dir(cfg.TokenFile), cacheFileName)
+	}
+
 	if len(cfg.Netatmo.ClientID) == 0 {
 		return Config{}, errNoNetatmoClientID
 	}
@@ -143,9 +362,97 @@ func Parse(args []string, getEnv func(string) string) (Config, error) {
 		return Config{}, fmt.Errorf("stale duration smaller than refresh interval: %s < %s", cfg.StaleDuration, cfg.RefreshInterval)
 	}
 
+	if !cfg.UnitSystem.Valid() {
+		return Config{}, errInvalidUnitSystem
+	}
+
+	if !cfg.MetricNaming.Valid() {
+		return Config{}, errInvalidMetricNaming
+	}
+
+	if !cfg.CachePolicy.Valid() {
+		return Config{}, errInvalidCachePolicy
+	}
+
+	if cfg.CachePolicy != collector.CachePolicyServeForever && cfg.CacheMaxAge <= 0 {
+		return Config{}, errInvalidCacheMaxAge
+	}
+
+	if cfg.RefreshHistory < 0 {
+		return Config{}, errInvalidRefreshHistory
+	}
+
+	if !validThresholds(cfg.WifiThresholds, len(signal.DefaultWifiThresholds)) {
+		return Config{}, errInvalidWifiThresholds
+	}
+
+	if !validThresholds(cfg.RFThresholds, len(signal.DefaultRFThresholds)) {
+		return Config{}, errInvalidRFThresholds
+	}
+
+	if cfg.MQTT.Enabled() {
+		if err := cfg.MQTT.Validate(); err != nil {
+			return Config{}, fmt.Errorf("error in MQTT settings: %w", err)
+		}
+	}
+
+	if cfg.RemoteWrite.Enabled() {
+		if err := cfg.RemoteWrite.Validate(); err != nil {
+			return Config{}, fmt.Errorf("error in remote write settings: %w", err)
+		}
+	}
+
+	if cfg.Influx.Enabled() {
+		if err := cfg.Influx.Validate(); err != nil {
+			return Config{}, fmt.Errorf("error in InfluxDB settings: %w", err)
+		}
+	}
+
 	return cfg, nil
 }
 
+func validThresholds(thresholds []int, count int) bool {
+	if len(thresholds) != count {
+		return false
+	}
+
+	for i := 1; i < len(thresholds); i++ {
+		if thresholds[i] >= thresholds[i-1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseIntList(value string) ([]int, error) {
+	var result []int
+	for _, part := range strings.Split(value, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, i)
+	}
+
+	return result, nil
+}
+
+func parseStringMap(value string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q needs to have the format key=value", part)
+		}
+
+		result[key] = value
+	}
+
+	return result, nil
+}
+
 func applyEnvironment(cfg *Config, getenv func(string) string) error {
 	if envAddr := getenv(envVarListenAddress); envAddr != "" {
 		cfg.Addr = envAddr
@@ -155,6 +462,10 @@ func applyEnvironment(cfg *Config, getenv func(string) string) error {
 		cfg.ExternalURL = externalURL
 	}
 
+	if configFile := getenv(envVarConfigFile); configFile != "" {
+		cfg.ConfigFile = configFile
+	}
+
 	if tokenFile := getenv(envVarTokenFile); tokenFile != "" {
 		cfg.TokenFile = tokenFile
 	}
@@ -187,6 +498,219 @@ func applyEnvironment(cfg *Config, getenv func(string) string) error {
 		cfg.StaleDuration = duration
 	}
 
+	if envWifiThresholds := getenv(envVarWifiThresholds); envWifiThresholds != "" {
+		thresholds, err := parseIntList(envWifiThresholds)
+		if err != nil {
+			return err
+		}
+
+		cfg.WifiThresholds = thresholds
+	}
+
+	if envRFThresholds := getenv(envVarRFThresholds); envRFThresholds != "" {
+		thresholds, err := parseIntList(envRFThresholds)
+		if err != nil {
+			return err
+		}
+
+		cfg.RFThresholds = thresholds
+	}
+
+	if envDerivedMetrics := getenv(envVarDerivedMetrics); envDerivedMetrics != "" {
+		cfg.DerivedMetrics = true
+	}
+
+	if envUnitSystem := getenv(envVarUnitSystem); envUnitSystem != "" {
+		cfg.UnitSystem = collector.UnitSystem(envUnitSystem)
+	}
+
+	if envMetricNaming := getenv(envVarMetricNaming); envMetricNaming != "" {
+		cfg.MetricNaming = collector.MetricNaming(envMetricNaming)
+	}
+
+	if envMetricTimestamps := getenv(envVarMetricTimestamps); envMetricTimestamps != "" {
+		cfg.MetricTimestamps = true
+	}
+
+	if envMetricPrefix := getenv(envVarMetricPrefix); envMetricPrefix != "" {
+		cfg.MetricPrefix = envMetricPrefix
+	}
+
+	if envLabelNames := getenv(envVarLabelNames); envLabelNames != "" {
+		labelNames, err := parseStringMap(envLabelNames)
+		if err != nil {
+			return err
+		}
+
+		cfg.LabelNames = labelNames
+	}
+
+	if envGoCollector := getenv(envVarGoCollector); envGoCollector != "" {
+		value, err := strconv.ParseBool(envGoCollector)
+		if err != nil {
+			return err
+		}
+
+		cfg.GoCollector = value
+	}
+
+	if envProcessCollector := getenv(envVarProcessCollector); envProcessCollector != "" {
+		value, err := strconv.ParseBool(envProcessCollector)
+		if err != nil {
+			return err
+		}
+
+		cfg.ProcessCollector = value
+	}
+
+	if envBuildInfoCollector := getenv(envVarBuildInfoCollector); envBuildInfoCollector != "" {
+		value, err := strconv.ParseBool(envBuildInfoCollector)
+		if err != nil {
+			return err
+		}
+
+		cfg.BuildInfoCollector = value
+	}
+
+	if envAPILimits := getenv(envVarAPILimits); envAPILimits != "" {
+		if err := cfg.APILimits.Set(envAPILimits); err != nil {
+			return err
+		}
+	}
+
+	if envRefreshHistory := getenv(envVarRefreshHistory); envRefreshHistory != "" {
+		size, err := strconv.Atoi(envRefreshHistory)
+		if err != nil {
+			return err
+		}
+
+		cfg.RefreshHistory = size
+	}
+
+	if envCachePolicy := getenv(envVarCachePolicy); envCachePolicy != "" {
+		cfg.CachePolicy = collector.CachePolicy(envCachePolicy)
+	}
+
+	if envCacheMaxAge := getenv(envVarCacheMaxAge); envCacheMaxAge != "" {
+		duration, err := time.ParseDuration(envCacheMaxAge)
+		if err != nil {
+			return err
+		}
+
+		cfg.CacheMaxAge = duration
+	}
+
+	if envCachePersist := getenv(envVarCachePersist); envCachePersist != "" {
+		cfg.CachePersist = true
+	}
+
+	if envMQTTBroker := getenv(envVarMQTTBroker); envMQTTBroker != "" {
+		cfg.MQTT.Broker = envMQTTBroker
+	}
+
+	if envMQTTClientID := getenv(envVarMQTTClientID); envMQTTClientID != "" {
+		cfg.MQTT.ClientID = envMQTTClientID
+	}
+
+	if envMQTTUsername := getenv(envVarMQTTUsername); envMQTTUsername != "" {
+		cfg.MQTT.Username = envMQTTUsername
+	}
+
+	if envMQTTPassword := getenv(envVarMQTTPassword); envMQTTPassword != "" {
+		cfg.MQTT.Password = envMQTTPassword
+	}
+
+	if envMQTTTopicPrefix := getenv(envVarMQTTTopicPrefix); envMQTTTopicPrefix != "" {
+		cfg.MQTT.TopicPrefix = envMQTTTopicPrefix
+	}
+
+	if envMQTTQoS := getenv(envVarMQTTQoS); envMQTTQoS != "" {
+		qos, err := strconv.Atoi(envMQTTQoS)
+		if err != nil {
+			return err
+		}
+
+		cfg.MQTT.QoS = qos
+	}
+
+	if envMQTTDiscovery := getenv(envVarMQTTDiscovery); envMQTTDiscovery != "" {
+		cfg.MQTT.Discovery = true
+	}
+
+	if envMQTTDiscoveryPrefix := getenv(envVarMQTTDiscoveryPrefix); envMQTTDiscoveryPrefix != "" {
+		cfg.MQTT.DiscoveryPrefix = envMQTTDiscoveryPrefix
+	}
+
+	if envMQTTCAFile := getenv(envVarMQTTCAFile); envMQTTCAFile != "" {
+		cfg.MQTT.TLS.CAFile = envMQTTCAFile
+	}
+
+	if envMQTTCertFile := getenv(envVarMQTTCertFile); envMQTTCertFile != "" {
+		cfg.MQTT.TLS.CertFile = envMQTTCertFile
+	}
+
+	if envMQTTKeyFile := getenv(envVarMQTTKeyFile); envMQTTKeyFile != "" {
+		cfg.MQTT.TLS.KeyFile = envMQTTKeyFile
+	}
+
+	if envMQTTInsecure := getenv(envVarMQTTInsecure); envMQTTInsecure != "" {
+		cfg.MQTT.TLS.InsecureSkipVerify = true
+	}
+
+	if envRemoteWriteURL := getenv(envVarRemoteWriteURL); envRemoteWriteURL != "" {
+		cfg.RemoteWrite.URL = envRemoteWriteURL
+	}
+
+	if envRemoteWriteUsername := getenv(envVarRemoteWriteUsername); envRemoteWriteUsername != "" {
+		cfg.RemoteWrite.Username = envRemoteWriteUsername
+	}
+
+	if envRemoteWritePassword := getenv(envVarRemoteWritePassword); envRemoteWritePassword != "" {
+		cfg.RemoteWrite.Password = envRemoteWritePassword
+	}
+
+	if envRemoteWriteToken := getenv(envVarRemoteWriteToken); envRemoteWriteToken != "" {
+		cfg.RemoteWrite.BearerToken = envRemoteWriteToken
+	}
+
+	if envRemoteWriteLabels := getenv(envVarRemoteWriteLabels); envRemoteWriteLabels != "" {
+		labels, err := parseStringMap(envRemoteWriteLabels)
+		if err != nil {
+			return err
+		}
+
+		cfg.RemoteWrite.ExternalLabels = labels
+	}
+
+	if envRemoteWriteQueue := getenv(envVarRemoteWriteQueue); envRemoteWriteQueue != "" {
+		size, err := strconv.Atoi(envRemoteWriteQueue)
+		if err != nil {
+			return err
+		}
+
+		cfg.RemoteWrite.QueueSize = size
+	}
+
+	if envInfluxHandler := getenv(envVarInfluxHandler); envInfluxHandler != "" {
+		cfg.Influx.Handler = true
+	}
+
+	if envInfluxURL := getenv(envVarInfluxURL); envInfluxURL != "" {
+		cfg.Influx.URL = envInfluxURL
+	}
+
+	if envInfluxOrg := getenv(envVarInfluxOrg); envInfluxOrg != "" {
+		cfg.Influx.Org = envInfluxOrg
+	}
+
+	if envInfluxBucket := getenv(envVarInfluxBucket); envInfluxBucket != "" {
+		cfg.Influx.Bucket = envInfluxBucket
+	}
+
+	if envInfluxToken := getenv(envVarInfluxToken); envInfluxToken != "" {
+		cfg.Influx.Token = envInfluxToken
+	}
+
 	if envClientID := getenv(envVarNetatmoClientID); envClientID != "" {
 		cfg.Netatmo.ClientID = envClientID
 	}
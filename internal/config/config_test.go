@@ -1,12 +1,21 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	netatmo "github.com/exzz/netatmo-api-go"
 	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/alert"
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+	"github.com/xperimental/netatmo-exporter/v2/internal/influx"
+	"github.com/xperimental/netatmo-exporter/v2/internal/mqtt"
+	"github.com/xperimental/netatmo-exporter/v2/internal/remotewrite"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -37,12 +46,26 @@ func TestParseConfig(t *testing.T) {
 			},
 			env: map[string]string{},
 			wantConfig: Config{
-				Addr:            defaultConfig.Addr,
-				ExternalURL:     "http://127.0.0.1:9210",
-				TokenFile:       "token-file",
-				LogLevel:        logLevel(logrus.InfoLevel),
-				RefreshInterval: defaultRefreshInterval,
-				StaleDuration:   defaultStaleDuration,
+				Addr:               defaultConfig.Addr,
+				ExternalURL:        "http://127.0.0.1:9210",
+				TokenFile:          "token-file",
+				LogLevel:           logLevel(logrus.InfoLevel),
+				RefreshInterval:    defaultRefreshInterval,
+				StaleDuration:      defaultStaleDuration,
+				WifiThresholds:     []int{86, 71},
+				RFThresholds:       []int{90, 80, 70},
+				UnitSystem:         collector.UnitSystemMetric,
+				MetricNaming:       collector.MetricNamingV1,
+				MetricPrefix:       collector.DefaultPrefix,
+				GoCollector:        true,
+				ProcessCollector:   true,
+				BuildInfoCollector: true,
+				APILimits:          api.DefaultLimits,
+				RefreshHistory:     collector.DefaultRefreshHistorySize,
+				CachePolicy:        collector.CachePolicyServeForever,
+				CacheMaxAge:        defaultCacheMaxAge,
+				MQTT:               defaultConfig.MQTT,
+				RemoteWrite:        defaultConfig.RemoteWrite,
 				Netatmo: netatmo.Config{
 					ClientID:     "id",
 					ClientSecret: "secret",
@@ -62,16 +85,105 @@ func TestParseConfig(t *testing.T) {
 				envVarLogLevel:            "debug",
 				envVarRefreshInterval:     "5m",
 				envVarStaleDuration:       "10m",
+				envVarWifiThresholds:      "80,70",
+				envVarRFThresholds:        "85, 75, 65",
+				envVarDerivedMetrics:      "true",
+				envVarUnitSystem:          "imperial",
+				envVarMetricNaming:        "dual",
+				envVarMetricTimestamps:    "true",
+				envVarMetricPrefix:        "weather_",
+				envVarLabelNames:          "station=site, module=sensor",
+				envVarGoCollector:         "false",
+				envVarProcessCollector:    "0",
+				envVarBuildInfoCollector:  "true",
+				envVarAPILimits:           "20/10s,200/1h",
+				envVarRefreshHistory:      "5",
+				envVarCachePolicy:         "serve-stale",
+				envVarCacheMaxAge:         "2h",
+				envVarCachePersist:        "true",
+				envVarMQTTBroker:          "ssl://broker.example.com:8883",
+				envVarMQTTClientID:        "weather",
+				envVarMQTTUsername:        "user",
+				envVarMQTTPassword:        "password",
+				envVarMQTTTopicPrefix:     "home/netatmo",
+				envVarMQTTQoS:             "1",
+				envVarMQTTDiscovery:       "true",
+				envVarMQTTDiscoveryPrefix: "ha",
+				envVarMQTTCAFile:          "ca.pem",
+				envVarMQTTCertFile:        "client.pem",
+				envVarMQTTKeyFile:         "client-key.pem",
+				envVarMQTTInsecure:        "true",
+				envVarRemoteWriteURL:      "https://mimir.example.com/api/v1/push",
+				envVarRemoteWriteToken:    "token",
+				envVarRemoteWriteLabels:   "site=home",
+				envVarRemoteWriteQueue:    "10",
+				envVarInfluxHandler:       "true",
+				envVarInfluxURL:           "http://influx:8086",
+				envVarInfluxOrg:           "home",
+				envVarInfluxBucket:        "netatmo",
+				envVarInfluxToken:         "influx-token",
 				envVarNetatmoClientID:     "id",
 				envVarNetatmoClientSecret: "secret",
 			},
 			wantConfig: Config{
-				Addr:            ":8080",
-				ExternalURL:     "http://example.com",
-				TokenFile:       "token.json",
-				LogLevel:        logLevel(logrus.DebugLevel),
-				RefreshInterval: 5 * time.Minute,
-				StaleDuration:   10 * time.Minute,
+				Addr:               ":8080",
+				ExternalURL:        "http://example.com",
+				TokenFile:          "token.json",
+				LogLevel:           logLevel(logrus.DebugLevel),
+				RefreshInterval:    5 * time.Minute,
+				StaleDuration:      10 * time.Minute,
+				WifiThresholds:     []int{80, 70},
+				RFThresholds:       []int{85, 75, 65},
+				DerivedMetrics:     true,
+				UnitSystem:         collector.UnitSystemImperial,
+				MetricNaming:       collector.MetricNamingDual,
+				MetricTimestamps:   true,
+				MetricPrefix:       "weather_",
+				BuildInfoCollector: true,
+				LabelNames: map[string]string{
+					"station": "site",
+					"module":  "sensor",
+				},
+				APILimits: api.Limits{
+					{Requests: 20, Window: 10 * time.Second},
+					{Requests: 200, Window: time.Hour},
+				},
+				RefreshHistory: 5,
+				CachePolicy:    collector.CachePolicyServeStale,
+				CacheMaxAge:    2 * time.Hour,
+				CachePersist:   true,
+				CacheFile:      "netatmo-cache.json",
+				MQTT: mqtt.Config{
+					Broker:          "ssl://broker.example.com:8883",
+					ClientID:        "weather",
+					Username:        "user",
+					Password:        "password",
+					TopicPrefix:     "home/netatmo",
+					QoS:             1,
+					Discovery:       true,
+					DiscoveryPrefix: "ha",
+					TLS: mqtt.TLSConfig{
+						CAFile:             "ca.pem",
+						CertFile:           "client.pem",
+						KeyFile:            "client-key.pem",
+						InsecureSkipVerify: true,
+					},
+				},
+				RemoteWrite: remotewrite.Config{
+					URL:         "https://mimir.example.com/api/v1/push",
+					BearerToken: "token",
+					ExternalLabels: map[string]string{
+						"site": "home",
+					},
+					QueueSize: 10,
+				},
+				Influx: influx.Config{
+					Handler: true,
+					URL:     "http://influx:8086",
+					Org:     "home",
+					Bucket:  "netatmo",
+					Token:   "influx-token",
+				},
 				Netatmo: netatmo.Config{
 					ClientID:     "id",
 					ClientSecret: "secret",
@@ -153,6 +265,205 @@ func TestParseConfig(t *testing.T) {
 			},
 			wantErr: errNoNetatmoClientSecret,
 		},
+		{
+			name: "invalid wifi thresholds",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagWifiThresholds,
+				"71,86",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidWifiThresholds,
+		},
+		{
+			name: "invalid rf thresholds",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+			},
+			env: map[string]string{
+				envVarRFThresholds: "90,80",
+			},
+			wantErr: errInvalidRFThresholds,
+		},
+		{
+			name: "invalid unit system",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagUnitSystem,
+				"nautical",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidUnitSystem,
+		},
+		{
+			name: "invalid metric naming",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagMetricNaming,
+				"v3",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidMetricNaming,
+		},
+		{
+			name: "invalid cache policy",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagCachePolicy,
+				"keep",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidCachePolicy,
+		},
+		{
+			name: "invalid cache max age",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagCachePolicy,
+				"drop",
+				"--" + flagCacheMaxAge,
+				"0s",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidCacheMaxAge,
+		},
+		{
+			name: "invalid refresh history",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagRefreshHistory,
+				"-1",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidRefreshHistory,
+		},
+		{
+			name: "persist cache",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"/data/token.json",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagCachePersist,
+			},
+			env: map[string]string{},
+			wantConfig: Config{
+				Addr:               defaultConfig.Addr,
+				ExternalURL:        "http://127.0.0.1:9210",
+				TokenFile:          "/data/token.json",
+				LogLevel:           logLevel(logrus.InfoLevel),
+				RefreshInterval:    defaultRefreshInterval,
+				StaleDuration:      defaultStaleDuration,
+				WifiThresholds:     []int{86, 71},
+				RFThresholds:       []int{90, 80, 70},
+				UnitSystem:         collector.UnitSystemMetric,
+				MetricNaming:       collector.MetricNamingV1,
+				MetricPrefix:       collector.DefaultPrefix,
+				GoCollector:        true,
+				ProcessCollector:   true,
+				BuildInfoCollector: true,
+				APILimits:          api.DefaultLimits,
+				RefreshHistory:     collector.DefaultRefreshHistorySize,
+				CachePolicy:        collector.CachePolicyServeForever,
+				CacheMaxAge:        defaultCacheMaxAge,
+				MQTT:               defaultConfig.MQTT,
+				RemoteWrite:        defaultConfig.RemoteWrite,
+				CachePersist:       true,
+				CacheFile:          "/data/netatmo-cache.json",
+				Netatmo: netatmo.Config{
+					ClientID:     "id",
+					ClientSecret: "secret",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "disabled api limits",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagAPILimits,
+				"none",
+			},
+			env: map[string]string{},
+			wantConfig: Config{
+				Addr:               defaultConfig.Addr,
+				ExternalURL:        "http://127.0.0.1:9210",
+				TokenFile:          "token-file",
+				LogLevel:           logLevel(logrus.InfoLevel),
+				RefreshInterval:    defaultRefreshInterval,
+				StaleDuration:      defaultStaleDuration,
+				WifiThresholds:     []int{86, 71},
+				RFThresholds:       []int{90, 80, 70},
+				UnitSystem:         collector.UnitSystemMetric,
+				MetricNaming:       collector.MetricNamingV1,
+				MetricPrefix:       collector.DefaultPrefix,
+				GoCollector:        true,
+				ProcessCollector:   true,
+				BuildInfoCollector: true,
+				APILimits:          api.Limits{},
+				RefreshHistory:     collector.DefaultRefreshHistorySize,
+				CachePolicy:        collector.CachePolicyServeForever,
+				CacheMaxAge:        defaultCacheMaxAge,
+				MQTT:               defaultConfig.MQTT,
+				RemoteWrite:        defaultConfig.RemoteWrite,
+				Netatmo: netatmo.Config{
+					ClientID:     "id",
+					ClientSecret: "secret",
+				},
+			},
+			wantErr: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -181,3 +492,233 @@ func TestParseConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestParseConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantFile File
+		wantErr  string
+	}{
+		{
+			name:     "empty",
+			contents: "",
+			wantFile: File{},
+		},
+		{
+			name: "module filters",
+			contents: `modules:
+  include:
+    - station: "Home*"
+  exclude:
+    - name: "/^Test/"
+    - type: NAModule3
+      home: Office
+`,
+			wantFile: File{
+				Modules: ModulesConfig{
+					Include: []collector.ModuleRule{
+						{Station: "Home*"},
+					},
+					Exclude: []collector.ModuleRule{
+						{Name: "/^Test/"},
+						{Type: "NAModule3", Home: "Office"},
+					},
+				},
+			},
+		},
+		{
+			name: "module labels",
+			contents: `modules:
+  labels:
+    "70:ee:50:00:00:01":
+      room: Kitchen
+      floor: "1"
+`,
+			wantFile: File{
+				Modules: ModulesConfig{
+					Labels: map[string]map[string]string{
+						"70:ee:50:00:00:01": {
+							"room":  "Kitchen",
+							"floor": "1",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "calibration",
+			contents: `modules:
+  calibration:
+    "70:ee:50:00:00:01":
+      temperature:
+        offset: -0.5
+      humidity:
+        offset: 3
+        factor: 1.02
+`,
+			wantFile: File{
+				Modules: ModulesConfig{
+					Calibration: map[string]collector.ModuleCalibration{
+						"70:ee:50:00:00:01": {
+							Temperature: &collector.Calibration{Offset: -0.5},
+							Humidity:    &collector.Calibration{Offset: 3, Factor: 1.02},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "plausibility rules",
+			contents: `plausibility:
+  pressure:
+    min: 800
+    max: 1100
+  temperature:
+    max_jump: 10
+`,
+			wantFile: File{
+				Plausibility: map[string]collector.PlausibilityRule{
+					"pressure": {
+						Min: float64Ptr(800),
+						Max: float64Ptr(1100),
+					},
+					"temperature": {
+						MaxJump: 10,
+					},
+				},
+			},
+		},
+		{
+			name: "alerts",
+			contents: `alerts:
+  rules:
+    - name: frost
+      module:
+        type: NAModule1
+      measurement: temperature
+      below: 0
+      hysteresis: 1
+      for: 15m
+  receivers:
+    - name: chat
+      url: https://hooks.example.com/test
+      format: slack
+`,
+			wantFile: File{
+				Alerts: alert.Config{
+					Rules: []alert.Rule{
+						{
+							Name:        "frost",
+							Module:      collector.ModuleRule{Type: "NAModule1"},
+							Measurement: "temperature",
+							Below:       float64Ptr(0),
+							Hysteresis:  1,
+							For:         15 * time.Minute,
+						},
+					},
+					Receivers: []alert.Receiver{
+						{
+							Name:   "chat",
+							URL:    "https://hooks.example.com/test",
+							Format: alert.FormatSlack,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown field",
+			contents: `modules:
+  hide:
+    - name: Test
+`,
+			wantErr: "error in configuration file: yaml: unmarshal errors:\n  line 2: field hide not found in type config.ModulesConfig",
+		},
+		{
+			name: "invalid filter",
+			contents: `modules:
+  exclude:
+    - name: "/[/"
+`,
+			wantErr: "error in module filters: error in exclude rules: rule 1: invalid regular expression \"/[/\": error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			name: "invalid plausibility rule",
+			contents: `plausibility:
+  co2:
+    min: 5000
+    max: 400
+`,
+			wantErr: "error in plausibility rules: co2: minimum 5000 is greater than maximum 400",
+		},
+		{
+			name: "invalid alert rule",
+			contents: `alerts:
+  rules:
+    - name: humid
+      measurement: humidity
+`,
+			wantErr: "error in alerts: rule \"humid\": needs either above or below",
+		},
+		{
+			name: "reserved label",
+			contents: `modules:
+  labels:
+    "70:ee:50:00:00:01":
+      station: Kitchen
+`,
+			wantErr: "error in module labels: module 70:ee:50:00:00:01: label name \"station\" is reserved",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configFile := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(configFile, []byte(tt.contents), 0o600); err != nil {
+				t.Fatalf("error writing config file: %s", err)
+			}
+
+			args := []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+			}
+			getenv := func(key string) string {
+				if key == envVarConfigFile {
+					return configFile
+				}
+
+				return ""
+			}
+
+			config, err := Parse(args, getenv)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got error %q, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if !reflect.DeepEqual(config.File, tt.wantFile) {
+				t.Errorf("got file %v, want %v", config.File, tt.wantFile)
+			}
+		})
+	}
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/alert"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+// File contains the settings which can only be set using the configuration file,
+// because they do not fit into a command line flag or environment variable.
+type File struct {
+	Modules ModulesConfig `yaml:"modules"`
+	// Plausibility contains the plausible values of measurements, keyed by the measurement name.
+	Plausibility map[string]collector.PlausibilityRule `yaml:"plausibility"`
+	Alerts       alert.Config                          `yaml:"alerts"`
+}
+
+// ModulesConfig contains settings applied to individual modules.
+type ModulesConfig struct {
+	Include []collector.ModuleRule `yaml:"include"`
+	Exclude []collector.ModuleRule `yaml:"exclude"`
+	// Labels contains additional labels for modules, keyed by the module ID.
+	Labels map[string]map[string]string `yaml:"labels"`
+	// Calibration contains corrections for the measurements of modules, keyed by the module ID.
+	Calibration map[string]collector.ModuleCalibration `yaml:"calibration"`
+}
+
+func loadFile(fileName string) (File, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return File{}, err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	var result File
+	if err := decoder.Decode(&result); err != nil && !errors.Is(err, io.EOF) {
+		return File{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,111 @@
+// Package derived contains calculations of meteorological values, which are not measured directly,
+// but can be derived from other measurements.
+package derived
+
+import (
+	"math"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/units"
+)
+
+const (
+	// Coefficients for the Magnus formula (Sonntag, 1990).
+	magnusA = 17.62
+	magnusB = 243.12
+
+	// Saturation vapor pressure at 0 °C in hectopascal.
+	saturationPressure = 6.112
+
+	// Conversion factor from vapor pressure (hPa) divided by temperature (K) to absolute humidity (g/m³).
+	absoluteHumidityFactor = 216.74
+
+	// Lowest temperatures for the heat index (in fahrenheit) and humidex (in celsius).
+	heatIndexMinimum = 80
+	humidexMinimum   = 20
+
+	// Constants of the barometric formula using the ICAO standard atmosphere.
+	pressureExponent = 0.190263
+	pressureFactor   = 8.417286e-5
+)
+
+// DewPoint returns the dew point in degrees celsius for the given temperature in celsius and relative humidity in percent.
+func DewPoint(temperature, humidity float64) float64 {
+	gamma := math.Log(humidity/100) + magnusA*temperature/(magnusB+temperature)
+	return magnusB * gamma / (magnusA - gamma)
+}
+
+// AbsoluteHumidity returns the mass of water vapor in grams per cubic meter of air for the given temperature in celsius
+// and relative humidity in percent.
+func AbsoluteHumidity(temperature, humidity float64) float64 {
+	vaporPressure := humidity / 100 * saturationPressure * math.Exp(magnusA*temperature/(magnusB+temperature))
+	return absoluteHumidityFactor * vaporPressure / (temperature + 273.15)
+}
+
+// HeatIndex returns the "apparent temperature" in degrees celsius for the given temperature in celsius and relative humidity in percent.
+// It uses the algorithm of the US National Weather Service, which combines the Rothfusz regression with a simpler formula for low heat index values.
+// The heat index is only defined for temperatures of at least 80 °F (26.7 °C). For lower temperatures the temperature is returned unchanged.
+func HeatIndex(temperature, humidity float64) float64 {
+	t := units.CelsiusToFahrenheit(temperature)
+	if t < heatIndexMinimum {
+		return temperature
+	}
+
+	simple := 0.5 * (t + 61.0 + (t-68.0)*1.2 + humidity*0.094)
+	if (simple+t)/2 < 80 {
+		return units.FahrenheitToCelsius(simple)
+	}
+
+	hi := -42.379 +
+		2.04901523*t +
+		10.14333127*humidity -
+		0.22475541*t*humidity -
+		0.00683783*t*t -
+		0.05481717*humidity*humidity +
+		0.00122874*t*t*humidity +
+		0.00085282*t*humidity*humidity -
+		0.00000199*t*t*humidity*humidity
+
+	switch {
+	case humidity < 13 && t >= 80 && t <= 112:
+		hi -= (13 - humidity) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+	case humidity > 85 && t >= 80 && t <= 87:
+		hi += (humidity - 85) / 10 * (87 - t) / 5
+	}
+
+	return units.FahrenheitToCelsius(hi)
+}
+
+// Humidex returns the humidex as used by the Meteorological Service of Canada for the given temperature and dew point in celsius.
+// The humidex is only used for temperatures of at least 20 °C and can not be lower than the temperature.
+// For lower temperatures or dry air the temperature is returned unchanged.
+func Humidex(temperature, dewPoint float64) float64 {
+	if temperature < humidexMinimum {
+		return temperature
+	}
+
+	vaporPressure := 6.11 * math.Exp(5417.7530*(1/273.16-1/(dewPoint+273.15)))
+	return math.Max(temperature, temperature+0.5555*(vaporPressure-10))
+}
+
+// WindChill returns the wind chill temperature in degrees celsius for the given temperature in celsius and wind speed in kilometers per hour.
+// The formula is only defined for temperatures at or below 10 °C and wind speeds above 4.8 km/h. For other values the temperature is returned unchanged.
+func WindChill(temperature, windSpeed float64) float64 {
+	if temperature > 10 || windSpeed <= 4.8 {
+		return temperature
+	}
+
+	v := math.Pow(windSpeed, 0.16)
+	return 13.12 + 0.6215*temperature - 11.37*v + 0.3965*temperature*v
+}
+
+// QNH reduces the pressure measured at the station (QFE) in hectopascal to sea level using the altitude of the station in meters.
+// The reduction uses the ICAO standard atmosphere, as is common in aviation.
+func QNH(qfe, altitude float64) float64 {
+	return math.Pow(math.Pow(qfe, pressureExponent)+pressureFactor*altitude, 1/pressureExponent)
+}
+
+// QFE calculates the pressure at the station in hectopascal from the sea-level pressure (QNH) using the altitude of the station in meters.
+// This is the inverse of QNH.
+func QFE(qnh, altitude float64) float64 {
+	return math.Pow(math.Pow(qnh, pressureExponent)-pressureFactor*altitude, 1/pressureExponent)
+}
@@ -0,0 +1,156 @@
+package derived
+
+import (
+	"math"
+	"testing"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/units"
+)
+
+func TestDewPoint(t *testing.T) {
+	tt := []struct {
+		temperature float64
+		humidity    float64
+		want        float64
+	}{
+		{temperature: 20, humidity: 50, want: 9.3},
+		{temperature: 25, humidity: 60, want: 16.7},
+		{temperature: 30, humidity: 80, want: 26.2},
+		{temperature: 0, humidity: 100, want: 0},
+		{temperature: -10, humidity: 70, want: -14.4},
+	}
+
+	for _, tc := range tt {
+		got := DewPoint(tc.temperature, tc.humidity)
+		assertClose(t, "dew point", tc.temperature, tc.humidity, got, tc.want, 0.1)
+	}
+}
+
+func TestAbsoluteHumidity(t *testing.T) {
+	tt := []struct {
+		temperature float64
+		humidity    float64
+		want        float64
+	}{
+		{temperature: 0, humidity: 100, want: 4.8},
+		{temperature: 20, humidity: 50, want: 8.6},
+		{temperature: 25, humidity: 100, want: 23.0},
+		{temperature: 30, humidity: 80, want: 24.2},
+	}
+
+	for _, tc := range tt {
+		got := AbsoluteHumidity(tc.temperature, tc.humidity)
+		assertClose(t, "absolute humidity", tc.temperature, tc.humidity, got, tc.want, 0.1)
+	}
+}
+
+func TestHeatIndex(t *testing.T) {
+	// Reference values from the heat index chart of the US National Weather Service (in fahrenheit).
+	tt := []struct {
+		temperature float64
+		humidity    float64
+		want        float64
+	}{
+		{temperature: 70, humidity: 50, want: 69},
+		{temperature: 80, humidity: 40, want: 80},
+		{temperature: 90, humidity: 60, want: 100},
+		{temperature: 96, humidity: 65, want: 121},
+		{temperature: 100, humidity: 50, want: 118},
+		// Below 80 °F the heat index is not defined and the temperature is used.
+		{temperature: 60, humidity: 90, want: 60},
+		{temperature: 14, humidity: 50, want: 14},
+	}
+
+	for _, tc := range tt {
+		got := units.CelsiusToFahrenheit(HeatIndex(units.FahrenheitToCelsius(tc.temperature), tc.humidity))
+		assertClose(t, "heat index", tc.temperature, tc.humidity, got, tc.want, 1)
+	}
+}
+
+func TestHumidex(t *testing.T) {
+	// Reference values from the humidex table of Environment Canada.
+	tt := []struct {
+		temperature float64
+		dewPoint    float64
+		want        float64
+	}{
+		{temperature: 25, dewPoint: 20, want: 33},
+		{temperature: 30, dewPoint: 15, want: 34},
+		{temperature: 35, dewPoint: 25, want: 47},
+		// Below 20 °C or in dry air the humidex is not used and the temperature is returned.
+		{temperature: 15, dewPoint: 10, want: 15},
+		{temperature: -10, dewPoint: -14, want: -10},
+		{temperature: 22, dewPoint: -5, want: 22},
+	}
+
+	for _, tc := range tt {
+		got := Humidex(tc.temperature, tc.dewPoint)
+		assertClose(t, "humidex", tc.temperature, tc.dewPoint, got, tc.want, 0.5)
+	}
+}
+
+func TestWindChill(t *testing.T) {
+	// Reference values from the wind chill table of Environment Canada.
+	tt := []struct {
+		temperature float64
+		windSpeed   float64
+		want        float64
+	}{
+		{temperature: 5, windSpeed: 40, want: -1},
+		{temperature: 0, windSpeed: 10, want: -3},
+		{temperature: -10, windSpeed: 20, want: -18},
+		{temperature: -20, windSpeed: 30, want: -33},
+		{temperature: 15, windSpeed: 40, want: 15},
+		{temperature: -5, windSpeed: 3, want: -5},
+	}
+
+	for _, tc := range tt {
+		got := WindChill(tc.temperature, tc.windSpeed)
+		assertClose(t, "wind chill", tc.temperature, tc.windSpeed, got, tc.want, 0.5)
+	}
+}
+
+func TestQNH(t *testing.T) {
+	// Reference values from the ICAO standard atmosphere.
+	tt := []struct {
+		qfe      float64
+		altitude float64
+		want     float64
+	}{
+		{qfe: 1013.25, altitude: 0, want: 1013.25},
+		{qfe: 1000, altitude: 100, want: 1011.9},
+		{qfe: 954.6, altitude: 500, want: 1013.25},
+		{qfe: 898.75, altitude: 1000, want: 1013.25},
+	}
+
+	for _, tc := range tt {
+		got := QNH(tc.qfe, tc.altitude)
+		assertClose(t, "qnh", tc.qfe, tc.altitude, got, tc.want, 0.1)
+	}
+}
+
+func TestQFE(t *testing.T) {
+	tt := []struct {
+		qnh      float64
+		altitude float64
+		want     float64
+	}{
+		{qnh: 1013.25, altitude: 0, want: 1013.25},
+		{qnh: 1013.25, altitude: 500, want: 954.6},
+		{qnh: 1013.25, altitude: 1000, want: 898.75},
+		{qnh: QNH(987, 300), altitude: 300, want: 987},
+	}
+
+	for _, tc := range tt {
+		got := QFE(tc.qnh, tc.altitude)
+		assertClose(t, "qfe", tc.qnh, tc.altitude, got, tc.want, 0.1)
+	}
+}
+
+func assertClose(t *testing.T, name string, a, b, got, want, tolerance float64) {
+	t.Helper()
+
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s(%v, %v): got %.2f, want %.2f", name, a, b, got, want)
+	}
+}
@@ -0,0 +1,20 @@
+package influx
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+// Handler creates a handler which outputs the current readings as line protocol.
+func Handler(log logrus.FieldLogger, readingsFunc func() []collector.Reading) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+		wr.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := Encode(wr, readingsFunc()); err != nil {
+			log.Errorf("Can not write line protocol response: %s", err)
+			return
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package influx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+func TestHandler(t *testing.T) {
+	golden, err := os.ReadFile(filepath.Join("testdata", "readings.golden"))
+	if err != nil {
+		t.Fatalf("error reading golden file: %s", err)
+	}
+
+	handler := Handler(logrus.New(), func() []collector.Reading {
+		return testReadings()
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/influx", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("got content type %q, want text/plain", got)
+	}
+
+	if got := rec.Body.String(); got != string(golden) {
+		t.Errorf("got body\n%s\nwant\n%s", got, golden)
+	}
+}
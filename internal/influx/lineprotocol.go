@@ -0,0 +1,88 @@
+// Package influx converts the sensor readings into the InfluxDB line protocol.
+package influx
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+// Measurement is the name of the InfluxDB measurement containing the readings.
+const Measurement = "netatmo"
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// point contains the readings of a single module.
+type point struct {
+	reading collector.Reading
+	fields  []collector.Reading
+}
+
+// Encode writes the readings as line protocol. Every module is written as one line with the module ID, name, station
+// and home as tags and the measurements as fields. The time of the last measurement is used as timestamp.
+func Encode(w io.Writer, readings []collector.Reading) error {
+	var points []*point
+	byID := make(map[string]*point)
+	for _, r := range readings {
+		p, ok := byID[r.Module.ID]
+		if !ok {
+			p = &point{
+				reading: r,
+			}
+			byID[r.Module.ID] = p
+			points = append(points, p)
+		}
+		p.fields = append(p.fields, r)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, p := range points {
+		writeLine(bw, p)
+	}
+
+	return bw.Flush()
+}
+
+func writeLine(w *bufio.Writer, p *point) {
+	w.WriteString(measurementEscaper.Replace(Measurement))
+
+	// Tags need to be sorted by key. Empty tag values are not allowed.
+	tags := [][2]string{
+		{"home", p.reading.HomeName},
+		{"id", p.reading.Module.ID},
+		{"module", p.reading.ModuleName},
+		{"station", p.reading.StationName},
+	}
+	for _, tag := range tags {
+		if tag[1] == "" {
+			continue
+		}
+
+		w.WriteByte(',')
+		w.WriteString(keyEscaper.Replace(tag[0]))
+		w.WriteByte('=')
+		w.WriteString(keyEscaper.Replace(tag[1]))
+	}
+
+	for i, field := range p.fields {
+		if i == 0 {
+			w.WriteByte(' ')
+		} else {
+			w.WriteByte(',')
+		}
+
+		w.WriteString(keyEscaper.Replace(field.Measurement))
+		w.WriteByte('=')
+		w.WriteString(strconv.FormatFloat(field.Value, 'f', -1, 64))
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatInt(p.reading.Time.UnixNano(), 10))
+	w.WriteByte('\n')
+}
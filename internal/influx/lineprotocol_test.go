@@ -0,0 +1,85 @@
+package influx
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+var update = flag.Bool("update", false, "Update golden files.")
+
+func testReadings() []collector.Reading {
+	main := &api.Device{
+		Device: netatmo.Device{
+			ID:   "70:ee:50:00:00:01",
+			Type: "NAMain",
+		},
+	}
+	outdoor := &api.Device{
+		Device: netatmo.Device{
+			ID:   "02:00:00:00:00:01",
+			Type: "NAModule1",
+		},
+	}
+	rain := &api.Device{
+		Device: netatmo.Device{
+			ID:   "05:00:00:00:00:01",
+			Type: "NAModule3",
+		},
+	}
+
+	reading := func(module *api.Device, name, station, home, measurement string, value float64, lastMeasure int64) collector.Reading {
+		return collector.Reading{
+			Module:      module,
+			ModuleName:  name,
+			StationName: station,
+			HomeName:    home,
+			Measurement: measurement,
+			Value:       value,
+			Time:        time.Unix(lastMeasure, 0),
+		}
+	}
+
+	return []collector.Reading{
+		reading(main, "Living Room", "Home (Living Room)", "Home", "temperature", 21.5, 1700000000),
+		reading(main, "Living Room", "Home (Living Room)", "Home", "humidity", 45, 1700000000),
+		reading(main, "Living Room", "Home (Living Room)", "Home", "co2", 612, 1700000000),
+		reading(main, "Living Room", "Home (Living Room)", "Home", "noise", 38, 1700000000),
+		reading(main, "Living Room", "Home (Living Room)", "Home", "pressure", 1013.2, 1700000000),
+		reading(outdoor, "Garden, North=Side", "Home (Living Room)", "Home", "temperature", -2.5, 1700000050),
+		reading(outdoor, "Garden, North=Side", "Home (Living Room)", "Home", "humidity", 80, 1700000050),
+		reading(outdoor, "Garden, North=Side", "Home (Living Room)", "Home", "battery", 64, 1700000050),
+		reading(rain, "Rain", "Cabin", "", "rain", 0.303, 1700000100),
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, testReadings()); err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	goldenFile := filepath.Join("testdata", "readings.golden")
+	if *update {
+		if err := os.WriteFile(goldenFile, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("error updating golden file: %s", err)
+		}
+	}
+
+	want, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("error reading golden file: %s", err)
+	}
+
+	if got := buf.String(); got != string(want) {
+		t.Errorf("got line protocol\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package influx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var (
+	errUnsupportedScheme = errors.New("URL needs to use http or https")
+	errNoOrg             = errors.New("need an organization")
+	errNoBucket          = errors.New("need a bucket")
+)
+
+// Config contains the settings for the InfluxDB output. Handler enables the endpoint serving the readings,
+// the other settings are used for writing to the InfluxDB v2 API.
+type Config struct {
+	Handler bool
+	URL     string
+	Org     string
+	Bucket  string
+	Token   string
+}
+
+// Enabled returns true, if a URL is configured.
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// Validate checks the settings for errors.
+func (c Config) Validate() error {
+	if _, err := c.writeURL(); err != nil {
+		return err
+	}
+
+	if c.Org == "" {
+		return errNoOrg
+	}
+
+	if c.Bucket == "" {
+		return errNoBucket
+	}
+
+	return nil
+}
+
+// writeURL returns the URL of the write endpoint including the parameters.
+func (c Config) writeURL() (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errUnsupportedScheme
+	}
+
+	u = u.JoinPath("api", "v2", "write")
+	u.RawQuery = url.Values{
+		"org":       []string{c.Org},
+		"bucket":    []string{c.Bucket},
+		"precision": []string{"ns"},
+	}.Encode()
+
+	return u.String(), nil
+}
+
+// Writer sends the readings to the InfluxDB v2 write API after each refresh.
+type Writer struct {
+	log    logrus.FieldLogger
+	client *http.Client
+	url    string
+	token  string
+	writes *prometheus.CounterVec
+	wg     sync.WaitGroup
+}
+
+// NewWriter creates a Writer from the configuration. The HTTP client is used for the requests.
+func NewWriter(log logrus.FieldLogger, cfg Config, client *http.Client) (*Writer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	writeURL, err := cfg.writeURL()
+	if err != nil {
+		return nil, err
+	}
+
+	writes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "netatmo_exporter_influx_writes_total",
+		Help: "Number of writes to InfluxDB by result.",
+	}, []string{"result"})
+	writes.WithLabelValues(resultSuccess)
+	writes.WithLabelValues(resultError)
+
+	return &Writer{
+		log:    log,
+		client: client,
+		url:    writeURL,
+		token:  cfg.Token,
+		writes: writes,
+	}, nil
+}
+
+// Write sends the readings to InfluxDB in the background.
+func (w *Writer) Write(_ time.Time, readings []collector.Reading) {
+	if len(readings) == 0 {
+		return
+	}
+
+	var body bytes.Buffer
+	if err := Encode(&body, readings); err != nil {
+		w.log.Errorf("Error encoding line protocol: %s", err)
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		if err := w.send(body.Bytes()); err != nil {
+			w.log.Errorf("Error writing to InfluxDB: %s", err)
+			w.writes.WithLabelValues(resultError).Inc()
+			return
+		}
+		w.writes.WithLabelValues(resultSuccess).Inc()
+	}()
+}
+
+// Wait blocks until all writes have finished.
+func (w *Writer) Wait() {
+	w.wg.Wait()
+}
+
+func (w *Writer) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+		return fmt.Errorf("unexpected status %s: %s", res.Status, bytes.TrimSpace(message))
+	}
+
+	return nil
+}
+
+// Describe implements prometheus.Collector
+func (w *Writer) Describe(ch chan<- *prometheus.Desc) {
+	w.writes.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (w *Writer) Collect(ch chan<- prometheus.Metric) {
+	w.writes.Collect(ch)
+}
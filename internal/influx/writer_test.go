@@ -0,0 +1,147 @@
+package influx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{
+		URL:    "http://localhost:8086",
+		Org:    "home",
+		Bucket: "netatmo",
+	}
+
+	tt := []struct {
+		desc    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{
+			desc:   "valid",
+			modify: func(*Config) {},
+		},
+		{
+			desc: "unsupported scheme",
+			modify: func(c *Config) {
+				c.URL = "udp://localhost:8089"
+			},
+			wantErr: errUnsupportedScheme.Error(),
+		},
+		{
+			desc: "no organization",
+			modify: func(c *Config) {
+				c.Org = ""
+			},
+			wantErr: errNoOrg.Error(),
+		},
+		{
+			desc: "no bucket",
+			modify: func(c *Config) {
+				c.Bucket = ""
+			},
+			wantErr: errNoBucket.Error(),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := valid
+			tc.modify(&cfg)
+
+			err := cfg.Validate()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %q, want none", err)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	type request struct {
+		path          string
+		query         string
+		authorization string
+		body          string
+	}
+
+	received := make(chan request, 2)
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{
+			path:          r.URL.Path,
+			query:         r.URL.RawQuery,
+			authorization: r.Header.Get("Authorization"),
+			body:          string(body),
+		}
+
+		if fail {
+			http.Error(w, "bucket not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w, err := NewWriter(logrus.New(), Config{
+		URL:    server.URL + "/influx/",
+		Org:    "home",
+		Bucket: "netatmo",
+		Token:  "secret",
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	golden, err := os.ReadFile(filepath.Join("testdata", "readings.golden"))
+	if err != nil {
+		t.Fatalf("error reading golden file: %s", err)
+	}
+
+	w.Write(time.Unix(3600, 0), testReadings())
+	w.Wait()
+	fail = true
+	w.Write(time.Unix(4200, 0), testReadings())
+	w.Wait()
+
+	want := request{
+		path:          "/influx/api/v2/write",
+		query:         "bucket=netatmo&org=home&precision=ns",
+		authorization: "Token secret",
+		body:          string(golden),
+	}
+	for i := 0; i < 2; i++ {
+		if got := <-received; got != want {
+			t.Errorf("request %d: got %+v, want %+v", i+1, got, want)
+		}
+	}
+
+	wantMetrics := `# HELP netatmo_exporter_influx_writes_total Number of writes to InfluxDB by result.
+# TYPE netatmo_exporter_influx_writes_total counter
+netatmo_exporter_influx_writes_total{result="error"} 1
+netatmo_exporter_influx_writes_total{result="success"} 1
+`
+	if err := testutil.CollectAndCompare(w, strings.NewReader(wantMetrics)); err != nil {
+		t.Error(err)
+	}
+}
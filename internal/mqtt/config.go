@@ -0,0 +1,121 @@
+// Package mqtt publishes the sensor readings to an MQTT broker.
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// DefaultClientID is the client ID used for connecting to the broker.
+	DefaultClientID = "netatmo-exporter"
+	// DefaultTopicPrefix is the first level of the topics the readings are published to.
+	DefaultTopicPrefix = "netatmo"
+	// DefaultDiscoveryPrefix is the topic prefix Home Assistant uses for MQTT discovery.
+	DefaultDiscoveryPrefix = "homeassistant"
+)
+
+var (
+	errNoTopicPrefix     = errors.New("topic prefix can not be empty")
+	errNoDiscoveryPrefix = errors.New("discovery prefix can not be empty")
+	errInvalidQoS        = errors.New("QoS needs to be 0, 1 or 2")
+	errIncompleteCert    = errors.New("need both certificate and key file for client certificate")
+)
+
+// Config contains the settings for connecting to the broker.
+type Config struct {
+	Broker          string
+	ClientID        string
+	Username        string
+	Password        string
+	TopicPrefix     string
+	QoS             int
+	Discovery       bool
+	DiscoveryPrefix string
+	TLS             TLSConfig
+}
+
+// TLSConfig contains the settings for encrypted connections to the broker.
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// Enabled returns true, if a broker is configured.
+func (c Config) Enabled() bool {
+	return c.Broker != ""
+}
+
+// Validate checks the settings for errors.
+func (c Config) Validate() error {
+	brokerURL, err := url.Parse(c.Broker)
+	if err != nil {
+		return fmt.Errorf("invalid broker URL: %w", err)
+	}
+
+	switch brokerURL.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return fmt.Errorf("unsupported broker URL scheme %q", brokerURL.Scheme)
+	}
+
+	if strings.Trim(c.TopicPrefix, "/") == "" {
+		return errNoTopicPrefix
+	}
+
+	if c.Discovery && strings.Trim(c.DiscoveryPrefix, "/") == "" {
+		return errNoDiscoveryPrefix
+	}
+
+	if c.QoS < 0 || c.QoS > 2 {
+		return errInvalidQoS
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errIncompleteCert
+	}
+
+	return nil
+}
+
+// tlsConfig creates the TLS configuration from the settings. It returns nil, if the defaults should be used.
+func (c TLSConfig) tlsConfig() (*tls.Config, error) {
+	if c == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	result := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.InsecureSkipVerify, //nolint: gosec
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		result.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		result.Certificates = []tls.Certificate{cert}
+	}
+
+	return result, nil
+}
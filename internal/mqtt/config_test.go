@@ -0,0 +1,82 @@
+package mqtt
+
+import (
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{
+		Broker:          "tcp://localhost:1883",
+		TopicPrefix:     DefaultTopicPrefix,
+		DiscoveryPrefix: DefaultDiscoveryPrefix,
+	}
+
+	tt := []struct {
+		desc    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{
+			desc:   "valid",
+			modify: func(*Config) {},
+		},
+		{
+			desc: "unsupported scheme",
+			modify: func(c *Config) {
+				c.Broker = "http://localhost:1883"
+			},
+			wantErr: "unsupported broker URL scheme \"http\"",
+		},
+		{
+			desc: "no topic prefix",
+			modify: func(c *Config) {
+				c.TopicPrefix = "/"
+			},
+			wantErr: errNoTopicPrefix.Error(),
+		},
+		{
+			desc: "no discovery prefix",
+			modify: func(c *Config) {
+				c.Discovery = true
+				c.DiscoveryPrefix = ""
+			},
+			wantErr: errNoDiscoveryPrefix.Error(),
+		},
+		{
+			desc: "invalid QoS",
+			modify: func(c *Config) {
+				c.QoS = 3
+			},
+			wantErr: errInvalidQoS.Error(),
+		},
+		{
+			desc: "certificate without key",
+			modify: func(c *Config) {
+				c.TLS.CertFile = "client.pem"
+			},
+			wantErr: errIncompleteCert.Error(),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := valid
+			tc.modify(&cfg)
+
+			err := cfg.Validate()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %q, want none", err)
+			}
+		})
+	}
+}
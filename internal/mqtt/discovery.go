@@ -0,0 +1,73 @@
+package mqtt
+
+import (
+	"fmt"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+// sensorClass describes how Home Assistant displays a measurement.
+type sensorClass struct {
+	name        string
+	deviceClass string
+	unit        string
+}
+
+// sensorClasses uses metric units, because the readings are always metric. The NetAtmo API reports metric values
+// independent of the units selected in the NetAtmo app and the exporter does not convert them for MQTT.
+var sensorClasses = map[string]sensorClass{
+	"temperature":       {name: "Temperature", deviceClass: "temperature", unit: "°C"},
+	"humidity":          {name: "Humidity", deviceClass: "humidity", unit: "%"},
+	"co2":               {name: "CO2", deviceClass: "carbon_dioxide", unit: "ppm"},
+	"noise":             {name: "Noise", deviceClass: "sound_pressure", unit: "dB"},
+	"pressure":          {name: "Pressure", deviceClass: "atmospheric_pressure", unit: "mbar"},
+	"absolute_pressure": {name: "Absolute pressure", deviceClass: "atmospheric_pressure", unit: "mbar"},
+	"wind_strength":     {name: "Wind strength", deviceClass: "wind_speed", unit: "km/h"},
+	"rain":              {name: "Rain", deviceClass: "precipitation", unit: "mm"},
+	"battery":           {name: "Battery", deviceClass: "battery", unit: "%"},
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// discoveryConfig is the payload of a Home Assistant MQTT discovery message for a sensor.
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	ObjectID          string          `json:"object_id"`
+	StateTopic        string          `json:"state_topic"`
+	ValueTemplate     string          `json:"value_template"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	UnitOfMeasurement string          `json:"unit_of_measurement,omitempty"`
+	StateClass        string          `json:"state_class"`
+	Device            discoveryDevice `json:"device"`
+}
+
+// discoveryTopic returns the topic of the discovery message for a measurement of a module.
+func (p *Publisher) discoveryTopic(id, measurement string) string {
+	return fmt.Sprintf("%s/sensor/netatmo_%s/%s/config", p.discoveryPrefix, id, measurement)
+}
+
+func (p *Publisher) newDiscoveryConfig(id string, reading collector.Reading) discoveryConfig {
+	class := sensorClasses[reading.Measurement]
+	return discoveryConfig{
+		Name:              class.name,
+		UniqueID:          fmt.Sprintf("netatmo_%s_%s", id, reading.Measurement),
+		ObjectID:          fmt.Sprintf("netatmo_%s_%s", id, reading.Measurement),
+		StateTopic:        p.stateTopic(id),
+		ValueTemplate:     fmt.Sprintf("{{ value_json.%s }}", reading.Measurement),
+		DeviceClass:       class.deviceClass,
+		UnitOfMeasurement: class.unit,
+		StateClass:        "measurement",
+		Device: discoveryDevice{
+			Identifiers:  []string{"netatmo_" + id},
+			Name:         reading.ModuleName,
+			Manufacturer: "Netatmo",
+			Model:        reading.Module.Type,
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package mqtt
+
+import (
+	"testing"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+func TestDiscoveryUnits(t *testing.T) {
+	p, err := New(logrus.New(), Config{
+		Broker:          "tcp://127.0.0.1:1883",
+		ClientID:        DefaultClientID,
+		TopicPrefix:     DefaultTopicPrefix,
+		Discovery:       true,
+		DiscoveryPrefix: DefaultDiscoveryPrefix,
+	})
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	// The readings are always metric, independent of the units configured in the NetAtmo app.
+	tt := []struct {
+		measurement string
+		wantUnit    string
+	}{
+		{measurement: "temperature", wantUnit: "°C"},
+		{measurement: "humidity", wantUnit: "%"},
+		{measurement: "co2", wantUnit: "ppm"},
+		{measurement: "noise", wantUnit: "dB"},
+		{measurement: "pressure", wantUnit: "mbar"},
+		{measurement: "absolute_pressure", wantUnit: "mbar"},
+		{measurement: "wind_strength", wantUnit: "km/h"},
+		{measurement: "rain", wantUnit: "mm"},
+		{measurement: "battery", wantUnit: "%"},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.measurement, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := p.newDiscoveryConfig("70ee50000001", collector.Reading{
+				Module: &api.Device{
+					Device: netatmo.Device{
+						ID:   "70:EE:50:00:00:01",
+						Type: "NAMain",
+					},
+				},
+				ModuleName:  "Living Room",
+				Measurement: tc.measurement,
+			})
+			if cfg.UnitOfMeasurement != tc.wantUnit {
+				t.Errorf("got unit %q, want %q", cfg.UnitOfMeasurement, tc.wantUnit)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+const (
+	publishTimeout = 10 * time.Second
+
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+// message is a single MQTT message.
+type message struct {
+	topic     string
+	payload   []byte
+	discovery bool
+}
+
+// Publisher sends the readings to an MQTT broker after each refresh. All messages are retained,
+// so that subscribers get the latest values immediately.
+type Publisher struct {
+	log             logrus.FieldLogger
+	client          paho.Client
+	qos             byte
+	topicPrefix     string
+	discovery       bool
+	discoveryPrefix string
+	messages        *prometheus.CounterVec
+
+	lock       sync.Mutex
+	discovered map[string]bool
+	wg         sync.WaitGroup
+}
+
+// New creates a Publisher from the configuration. The connection is established by Connect.
+func New(log logrus.FieldLogger, cfg Config) (*Publisher, error) {
+	tlsConfig, err := cfg.TLS.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Publisher{
+		log:             log,
+		qos:             byte(cfg.QoS),
+		topicPrefix:     strings.Trim(cfg.TopicPrefix, "/"),
+		discovery:       cfg.Discovery,
+		discoveryPrefix: strings.Trim(cfg.DiscoveryPrefix, "/"),
+		discovered:      make(map[string]bool),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOrderMatters(false).
+		SetOnConnectHandler(func(paho.Client) {
+			log.Infof("Connected to MQTT broker %s", cfg.Broker)
+			// The broker might have lost the retained discovery messages, so send them again.
+			p.resetDiscovered()
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Errorf("Lost connection to MQTT broker: %s", err)
+		})
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	messages := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "netatmo_exporter_mqtt_messages_total",
+		Help: "Number of messages published to the MQTT broker by result.",
+	}, []string{"result"})
+	messages.WithLabelValues(resultSuccess)
+	messages.WithLabelValues(resultError)
+	p.messages = messages
+	p.client = paho.NewClient(opts)
+
+	return p, nil
+}
+
+// Connect starts connecting to the broker. If the broker is not reachable, the client keeps retrying in the background.
+func (p *Publisher) Connect() error {
+	token := p.client.Connect()
+	if !token.WaitTimeout(publishTimeout) {
+		p.log.Warn("Connection to MQTT broker not established yet, retrying in background.")
+		return nil
+	}
+
+	return token.Error()
+}
+
+// Close waits for pending messages and disconnects from the broker.
+func (p *Publisher) Close() {
+	p.wg.Wait()
+	p.client.Disconnect(uint(publishTimeout.Milliseconds()))
+}
+
+// Publish sends the readings to the broker. Every module gets a JSON object with all readings on
+// "<prefix>/<id>" and a topic per measurement on "<prefix>/<id>/<measurement>".
+// The messages are sent in the background.
+func (p *Publisher) Publish(_ time.Time, readings []collector.Reading) {
+	msgs := p.messagesFor(readings)
+	if len(msgs) == 0 {
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		for _, m := range msgs {
+			token := p.client.Publish(m.topic, p.qos, true, m.payload)
+			if err := waitToken(token); err != nil {
+				p.log.Errorf("Error publishing to %s: %s", m.topic, err)
+				p.messages.WithLabelValues(resultError).Inc()
+				continue
+			}
+			p.messages.WithLabelValues(resultSuccess).Inc()
+
+			if m.discovery {
+				p.markDiscovered(m.topic)
+			}
+		}
+	}()
+}
+
+// Wait blocks until all messages have been sent.
+func (p *Publisher) Wait() {
+	p.wg.Wait()
+}
+
+func waitToken(token paho.Token) error {
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timeout after %s", publishTimeout)
+	}
+
+	return token.Error()
+}
+
+// moduleState is the JSON object published for a module.
+type moduleState struct {
+	id      string
+	reading collector.Reading
+	values  map[string]float64
+}
+
+func (s moduleState) MarshalJSON() ([]byte, error) {
+	result := map[string]any{
+		"id":      s.reading.Module.ID,
+		"module":  s.reading.ModuleName,
+		"station": s.reading.StationName,
+		"home":    s.reading.HomeName,
+		"type":    s.reading.Module.Type,
+		"time":    s.reading.Time.UTC().Format(time.RFC3339),
+	}
+	for name, value := range s.values {
+		result[name] = value
+	}
+
+	return json.Marshal(result)
+}
+
+// messagesFor creates the messages for the readings including discovery messages for new sensors.
+func (p *Publisher) messagesFor(readings []collector.Reading) []message {
+	var states []*moduleState
+	byID := make(map[string]*moduleState)
+	var msgs []message
+	for _, r := range readings {
+		id := topicID(r.Module.ID)
+		state, ok := byID[id]
+		if !ok {
+			state = &moduleState{
+				id:      id,
+				reading: r,
+				values:  make(map[string]float64),
+			}
+			byID[id] = state
+			states = append(states, state)
+		}
+		state.values[r.Measurement] = r.Value
+
+		msgs = append(msgs, message{
+			topic:   fmt.Sprintf("%s/%s/%s", p.topicPrefix, id, r.Measurement),
+			payload: []byte(strconv.FormatFloat(r.Value, 'f', -1, 64)),
+		})
+
+		if discovery, ok := p.discoveryMessage(id, r); ok {
+			msgs = append(msgs, discovery)
+		}
+	}
+
+	for _, state := range states {
+		payload, err := json.Marshal(state)
+		if err != nil {
+			p.log.Errorf("Error encoding state of %s: %s", state.id, err)
+			continue
+		}
+
+		msgs = append(msgs, message{
+			topic:   p.stateTopic(state.id),
+			payload: payload,
+		})
+	}
+
+	return msgs
+}
+
+// discoveryMessage returns the discovery message for the sensor, if it has not been sent successfully before.
+func (p *Publisher) discoveryMessage(id string, r collector.Reading) (message, bool) {
+	if !p.discovery {
+		return message{}, false
+	}
+
+	topic := p.discoveryTopic(id, r.Measurement)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.discovered[topic] {
+		return message{}, false
+	}
+
+	payload, err := json.Marshal(p.newDiscoveryConfig(id, r))
+	if err != nil {
+		p.log.Errorf("Error encoding discovery of %s: %s", topic, err)
+		return message{}, false
+	}
+
+	return message{
+		topic:     topic,
+		payload:   payload,
+		discovery: true,
+	}, true
+}
+
+// markDiscovered records that the discovery message on the topic has been delivered.
+func (p *Publisher) markDiscovered(topic string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.discovered[topic] = true
+}
+
+// resetDiscovered causes all discovery messages to be sent again with the next readings.
+func (p *Publisher) resetDiscovered() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	clear(p.discovered)
+}
+
+func (p *Publisher) stateTopic(id string) string {
+	return p.topicPrefix + "/" + id
+}
+
+// topicID converts the module ID into a form which can be used in topics.
+func topicID(id string) string {
+	return strings.ReplaceAll(strings.ToLower(id), ":", "")
+}
+
+// Describe implements prometheus.Collector
+func (p *Publisher) Describe(ch chan<- *prometheus.Desc) {
+	p.messages.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (p *Publisher) Collect(ch chan<- prometheus.Metric) {
+	p.messages.Collect(ch)
+}
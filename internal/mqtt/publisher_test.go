@@ -0,0 +1,268 @@
+package mqtt
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+type published struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+// testBroker is a minimal MQTT broker, which checks the credentials and records the published messages.
+type testBroker struct {
+	t        *testing.T
+	listener net.Listener
+	username string
+	password string
+	messages chan published
+}
+
+func newTestBroker(t *testing.T, username, password string) *testBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error starting broker: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	b := &testBroker{
+		t:        t,
+		listener: listener,
+		username: username,
+		password: password,
+		messages: make(chan published, 100),
+	}
+	go b.serve()
+
+	return b
+}
+
+func (b *testBroker) URL() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *testBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go b.handle(conn)
+	}
+}
+
+func (b *testBroker) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		var reply packets.ControlPacket
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+			if p.Username != b.username || string(p.Password) != b.password {
+				connack.ReturnCode = packets.ErrRefusedNotAuthorised
+			}
+			reply = connack
+		case *packets.PublishPacket:
+			b.messages <- published{
+				topic:   p.TopicName,
+				payload: string(p.Payload),
+				retain:  p.Retain,
+			}
+			if p.Qos > 0 {
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				reply = puback
+			}
+		case *packets.PingreqPacket:
+			reply = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+
+		if reply != nil {
+			if err := reply.Write(conn); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (b *testBroker) receive(count int) map[string]published {
+	b.t.Helper()
+
+	result := make(map[string]published, count)
+	for len(result) < count {
+		select {
+		case m := <-b.messages:
+			result[m.topic] = m
+		case <-time.After(5 * time.Second):
+			b.t.Fatalf("timeout after %d of %d messages", len(result), count)
+		}
+	}
+
+	return result
+}
+
+var testReadings = []collector.Reading{
+	{
+		Module: &api.Device{
+			Device: netatmo.Device{
+				ID:   "70:EE:50:00:00:01",
+				Type: "NAModule1",
+			},
+		},
+		ModuleName:  "Outside",
+		StationName: "Home (Living Room)",
+		HomeName:    "Home",
+		Measurement: "temperature",
+		Value:       -2.5,
+		Time:        time.Unix(3600, 0),
+	},
+	{
+		Module: &api.Device{
+			Device: netatmo.Device{
+				ID:   "70:EE:50:00:00:01",
+				Type: "NAModule1",
+			},
+		},
+		ModuleName:  "Outside",
+		StationName: "Home (Living Room)",
+		HomeName:    "Home",
+		Measurement: "battery",
+		Value:       80,
+		Time:        time.Unix(3600, 0),
+	},
+}
+
+func TestPublisher(t *testing.T) {
+	tt := []struct {
+		desc         string
+		discovery    bool
+		wantMessages map[string]published
+	}{
+		{
+			desc: "readings",
+			wantMessages: map[string]published{
+				"netatmo/70ee50000001": {
+					payload: `{"battery":80,"home":"Home","id":"70:EE:50:00:00:01","module":"Outside","station":"Home (Living Room)","temperature":-2.5,"time":"1970-01-01T01:00:00Z","type":"NAModule1"}`,
+				},
+				"netatmo/70ee50000001/temperature": {
+					payload: "-2.5",
+				},
+				"netatmo/70ee50000001/battery": {
+					payload: "80",
+				},
+			},
+		},
+		{
+			desc:      "discovery",
+			discovery: true,
+			wantMessages: map[string]published{
+				"netatmo/70ee50000001": {
+					payload: `{"battery":80,"home":"Home","id":"70:EE:50:00:00:01","module":"Outside","station":"Home (Living Room)","temperature":-2.5,"time":"1970-01-01T01:00:00Z","type":"NAModule1"}`,
+				},
+				"netatmo/70ee50000001/temperature": {
+					payload: "-2.5",
+				},
+				"netatmo/70ee50000001/battery": {
+					payload: "80",
+				},
+				"homeassistant/sensor/netatmo_70ee50000001/temperature/config": {
+					payload: `{"name":"Temperature","unique_id":"netatmo_70ee50000001_temperature","object_id":"netatmo_70ee50000001_temperature","state_topic":"netatmo/70ee50000001","value_template":"{{ value_json.temperature }}","device_class":"temperature","unit_of_measurement":"°C","state_class":"measurement","device":{"identifiers":["netatmo_70ee50000001"],"name":"Outside","manufacturer":"Netatmo","model":"NAModule1"}}`,
+				},
+				"homeassistant/sensor/netatmo_70ee50000001/battery/config": {
+					payload: `{"name":"Battery","unique_id":"netatmo_70ee50000001_battery","object_id":"netatmo_70ee50000001_battery","state_topic":"netatmo/70ee50000001","value_template":"{{ value_json.battery }}","device_class":"battery","unit_of_measurement":"%","state_class":"measurement","device":{"identifiers":["netatmo_70ee50000001"],"name":"Outside","manufacturer":"Netatmo","model":"NAModule1"}}`,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			broker := newTestBroker(t, "user", "secret")
+			p, err := New(logrus.New(), Config{
+				Broker:          broker.URL(),
+				ClientID:        DefaultClientID,
+				Username:        "user",
+				Password:        "secret",
+				TopicPrefix:     DefaultTopicPrefix,
+				QoS:             1,
+				Discovery:       tc.discovery,
+				DiscoveryPrefix: DefaultDiscoveryPrefix,
+			})
+			if err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+
+			if err := p.Connect(); err != nil {
+				t.Fatalf("got error %q, want none", err)
+			}
+			defer p.Close()
+
+			p.Publish(time.Unix(3600, 0), testReadings)
+			p.Wait()
+			got := broker.receive(len(tc.wantMessages))
+			for topic, want := range tc.wantMessages {
+				want.topic = topic
+				want.retain = true
+				if got[topic] != want {
+					t.Errorf("got message %+v, want %+v", got[topic], want)
+				}
+			}
+
+			// Discovery messages are only sent once.
+			p.Publish(time.Unix(4200, 0), testReadings)
+			p.Wait()
+			for topic := range broker.receive(3) {
+				if strings.HasPrefix(topic, DefaultDiscoveryPrefix) {
+					t.Errorf("got repeated discovery message on %s", topic)
+				}
+			}
+
+			// After a reconnect all messages are sent again.
+			p.resetDiscovered()
+			p.Publish(time.Unix(4800, 0), testReadings)
+			p.Wait()
+			got = broker.receive(len(tc.wantMessages))
+			for topic := range tc.wantMessages {
+				if _, ok := got[topic]; !ok {
+					t.Errorf("got no message on %s after reconnect", topic)
+				}
+			}
+
+			wantMetrics := `# HELP netatmo_exporter_mqtt_messages_total Number of messages published to the MQTT broker by result.
+# TYPE netatmo_exporter_mqtt_messages_total counter
+netatmo_exporter_mqtt_messages_total{result="error"} 0
+netatmo_exporter_mqtt_messages_total{result="success"} ` + strconv.Itoa(2*len(tc.wantMessages)+3) + `
+`
+			if err := testutil.CollectAndCompare(p, strings.NewReader(wantMetrics)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
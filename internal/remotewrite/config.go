@@ -0,0 +1,74 @@
+// Package remotewrite pushes the metrics to a server using the Prometheus remote write protocol.
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+// DefaultQueueSize is the number of requests kept for retrying, when the server is not reachable.
+const DefaultQueueSize = 100
+
+var (
+	errBasicAndBearer    = errors.New("can not use basic authentication and bearer token together")
+	errNoUsername        = errors.New("password needs a username")
+	errInvalidQueueSize  = errors.New("queue size needs to be positive")
+	errUnsupportedScheme = errors.New("URL needs to use http or https")
+)
+
+// Config contains the settings for pushing the metrics.
+type Config struct {
+	URL            string
+	Username       string
+	Password       string
+	BearerToken    string
+	ExternalLabels map[string]string
+	QueueSize      int
+}
+
+// Enabled returns true, if a URL is configured.
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// Validate checks the settings for errors.
+func (c Config) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errUnsupportedScheme
+	}
+
+	if c.Password != "" && c.Username == "" {
+		return errNoUsername
+	}
+
+	if c.Username != "" && c.BearerToken != "" {
+		return errBasicAndBearer
+	}
+
+	if c.QueueSize <= 0 {
+		return errInvalidQueueSize
+	}
+
+	names := make([]string, 0, len(c.ExternalLabels))
+	for name := range c.ExternalLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !collector.ValidLabelName(name) {
+			return fmt.Errorf("invalid external label name %q", name)
+		}
+	}
+
+	return nil
+}
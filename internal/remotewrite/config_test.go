@@ -0,0 +1,81 @@
+package remotewrite
+
+import (
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{
+		URL:       "https://mimir.example.com/api/v1/push",
+		QueueSize: DefaultQueueSize,
+	}
+
+	tt := []struct {
+		desc    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{
+			desc:   "valid",
+			modify: func(*Config) {},
+		},
+		{
+			desc: "unsupported scheme",
+			modify: func(c *Config) {
+				c.URL = "ftp://mimir.example.com/"
+			},
+			wantErr: errUnsupportedScheme.Error(),
+		},
+		{
+			desc: "password without username",
+			modify: func(c *Config) {
+				c.Password = "secret"
+			},
+			wantErr: errNoUsername.Error(),
+		},
+		{
+			desc: "basic and bearer",
+			modify: func(c *Config) {
+				c.Username = "user"
+				c.BearerToken = "token"
+			},
+			wantErr: errBasicAndBearer.Error(),
+		},
+		{
+			desc: "invalid queue size",
+			modify: func(c *Config) {
+				c.QueueSize = 0
+			},
+			wantErr: errInvalidQueueSize.Error(),
+		},
+		{
+			desc: "invalid external label",
+			modify: func(c *Config) {
+				c.ExternalLabels = map[string]string{"__name__": "test"}
+			},
+			wantErr: "invalid external label name \"__name__\"",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := valid
+			tc.modify(&cfg)
+
+			err := cfg.Validate()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %q, want %q", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %q, want none", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const labelName = "__name__"
+
+// toTimeSeries converts the gathered metrics into time series. Metrics without a timestamp get the current time.
+// External labels are only added, if the series does not have a label with the same name.
+func toTimeSeries(families []*dto.MetricFamily, externalLabels map[string]string, now time.Time) []timeSeries {
+	var result []timeSeries
+	add := func(m *dto.Metric, name string, value float64, extra ...label) {
+		timestamp := now.UnixMilli()
+		if m.TimestampMs != nil {
+			timestamp = m.GetTimestampMs()
+		}
+
+		result = append(result, timeSeries{
+			labels: seriesLabels(name, m.GetLabel(), extra, externalLabels),
+			samples: []sample{
+				{value: value, timestamp: timestamp},
+			},
+		})
+	}
+
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.GetMetric() {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(m, name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(m, name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(m, name, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := m.GetSummary()
+				for _, q := range summary.GetQuantile() {
+					add(m, name, q.GetValue(), label{name: "quantile", value: formatFloat(q.GetQuantile())})
+				}
+				add(m, name+"_sum", summary.GetSampleSum())
+				add(m, name+"_count", float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				histogram := m.GetHistogram()
+				hasInf := false
+				for _, b := range histogram.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						hasInf = true
+					}
+					add(m, name+"_bucket", float64(b.GetCumulativeCount()), label{name: "le", value: formatFloat(b.GetUpperBound())})
+				}
+				if !hasInf {
+					add(m, name+"_bucket", float64(histogram.GetSampleCount()), label{name: "le", value: "+Inf"})
+				}
+				add(m, name+"_sum", histogram.GetSampleSum())
+				add(m, name+"_count", float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+
+	return result
+}
+
+// seriesLabels returns the labels of a series sorted by name, as required by the remote write protocol.
+func seriesLabels(name string, pairs []*dto.LabelPair, extra []label, externalLabels map[string]string) []label {
+	result := make([]label, 0, len(pairs)+len(extra)+len(externalLabels)+1)
+	result = append(result, label{name: labelName, value: name})
+	present := map[string]bool{labelName: true}
+	for _, p := range pairs {
+		result = append(result, label{name: p.GetName(), value: p.GetValue()})
+		present[p.GetName()] = true
+	}
+	for _, l := range extra {
+		result = append(result, l)
+		present[l.name] = true
+	}
+	for name, value := range externalLabels {
+		if !present[name] {
+			result = append(result, label{name: name, value: value})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+
+	return result
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
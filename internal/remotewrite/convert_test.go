@@ -0,0 +1,74 @@
+package remotewrite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestToTimeSeries(t *testing.T) {
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "refresh_duration_seconds",
+		Help:    "Duration of refreshes.",
+		Buckets: []float64{0.5, 1},
+	})
+	histogram.Observe(0.2)
+	histogram.Observe(2)
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "refresh_total",
+		Help:        "Number of refreshes.",
+		ConstLabels: prometheus.Labels{"cluster": "local"},
+	})
+	counter.Add(3)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(histogram, counter)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	series := func(value float64, labels ...label) timeSeries {
+		return timeSeries{
+			labels:  labels,
+			samples: []sample{{value: value, timestamp: 3600000}},
+		}
+	}
+	want := []timeSeries{
+		series(1,
+			label{name: "__name__", value: "refresh_duration_seconds_bucket"},
+			label{name: "cluster", value: "remote"},
+			label{name: "env", value: "test"},
+			label{name: "le", value: "0.5"}),
+		series(1,
+			label{name: "__name__", value: "refresh_duration_seconds_bucket"},
+			label{name: "cluster", value: "remote"},
+			label{name: "env", value: "test"},
+			label{name: "le", value: "1"}),
+		series(2,
+			label{name: "__name__", value: "refresh_duration_seconds_bucket"},
+			label{name: "cluster", value: "remote"},
+			label{name: "env", value: "test"},
+			label{name: "le", value: "+Inf"}),
+		series(2.2,
+			label{name: "__name__", value: "refresh_duration_seconds_sum"},
+			label{name: "cluster", value: "remote"},
+			label{name: "env", value: "test"}),
+		series(2,
+			label{name: "__name__", value: "refresh_duration_seconds_count"},
+			label{name: "cluster", value: "remote"},
+			label{name: "env", value: "test"}),
+		series(3,
+			label{name: "__name__", value: "refresh_total"},
+			label{name: "cluster", value: "local"},
+			label{name: "env", value: "test"}),
+	}
+
+	got := toTimeSeries(families, map[string]string{"env": "test", "cluster": "remote"}, time.Unix(3600, 0))
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(timeSeries{}, label{}, sample{})); diff != "" {
+		t.Errorf("series differ: %s", diff)
+	}
+}
@@ -0,0 +1,62 @@
+package remotewrite
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// label, sample and timeSeries mirror the messages of the remote write protocol (prometheus.WriteRequest).
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// encodeWriteRequest creates the protobuf encoding of a WriteRequest containing the time series.
+func encodeWriteRequest(series []timeSeries) []byte {
+	var result []byte
+	for _, ts := range series {
+		result = protowire.AppendTag(result, 1, protowire.BytesType)
+		result = protowire.AppendBytes(result, encodeTimeSeries(ts))
+	}
+
+	return result
+}
+
+func encodeTimeSeries(ts timeSeries) []byte {
+	var result []byte
+	for _, l := range ts.labels {
+		var msg []byte
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, l.name)
+		msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+		msg = protowire.AppendString(msg, l.value)
+
+		result = protowire.AppendTag(result, 1, protowire.BytesType)
+		result = protowire.AppendBytes(result, msg)
+	}
+
+	for _, s := range ts.samples {
+		var msg []byte
+		msg = protowire.AppendTag(msg, 1, protowire.Fixed64Type)
+		msg = protowire.AppendFixed64(msg, math.Float64bits(s.value))
+		msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(s.timestamp))
+
+		result = protowire.AppendTag(result, 2, protowire.BytesType)
+		result = protowire.AppendBytes(result, msg)
+	}
+
+	return result
+}
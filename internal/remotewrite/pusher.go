@@ -0,0 +1,234 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+
+	resultSuccess = "success"
+	resultRetry   = "retry"
+	resultError   = "error"
+)
+
+// recoverableError is returned for failed requests, which should be retried.
+type recoverableError struct {
+	error
+}
+
+// batch is a compressed WriteRequest waiting to be sent.
+type batch struct {
+	body []byte
+}
+
+// Pusher sends the metrics of a gatherer to a remote write endpoint after each refresh.
+// Requests which fail because of network problems or server errors are kept in a queue and retried.
+type Pusher struct {
+	log            logrus.FieldLogger
+	client         *http.Client
+	gatherer       prometheus.Gatherer
+	url            string
+	username       string
+	password       string
+	bearerToken    string
+	externalLabels map[string]string
+	queueSize      int
+	minBackoff     time.Duration
+
+	requests *prometheus.CounterVec
+	dropped  prometheus.Counter
+
+	lock   sync.Mutex
+	queue  []*batch
+	notify chan struct{}
+}
+
+// New creates a Pusher sending the metrics of the gatherer. The HTTP client is used for the requests.
+func New(log logrus.FieldLogger, cfg Config, gatherer prometheus.Gatherer, client *http.Client) (*Pusher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "netatmo_exporter_remote_write_requests_total",
+		Help: "Number of remote write requests by result.",
+	}, []string{"result"})
+	requests.WithLabelValues(resultSuccess)
+	requests.WithLabelValues(resultRetry)
+	requests.WithLabelValues(resultError)
+
+	return &Pusher{
+		log:            log,
+		client:         client,
+		gatherer:       gatherer,
+		url:            cfg.URL,
+		username:       cfg.Username,
+		password:       cfg.Password,
+		bearerToken:    cfg.BearerToken,
+		externalLabels: cfg.ExternalLabels,
+		queueSize:      cfg.QueueSize,
+		minBackoff:     minBackoff,
+		requests:       requests,
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "netatmo_exporter_remote_write_dropped_total",
+			Help: "Number of remote write requests dropped because the queue was full.",
+		}),
+		notify: make(chan struct{}, 1),
+	}, nil
+}
+
+// Push gathers the metrics and adds them to the queue. The requests are sent by Run.
+func (p *Pusher) Push(now time.Time, _ []collector.Reading) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		p.log.Errorf("Error gathering metrics for remote write: %s", err)
+		if len(families) == 0 {
+			return
+		}
+	}
+
+	series := toTimeSeries(families, p.externalLabels, now)
+	if len(series) == 0 {
+		return
+	}
+
+	b := &batch{
+		body: snappy.Encode(nil, encodeWriteRequest(series)),
+	}
+
+	p.lock.Lock()
+	if len(p.queue) >= p.queueSize {
+		p.log.Warn("Remote write queue is full, dropping oldest request.")
+		p.queue = p.queue[1:]
+		p.dropped.Inc()
+	}
+	p.queue = append(p.queue, b)
+	p.lock.Unlock()
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends the queued requests until the context is canceled.
+func (p *Pusher) Run(ctx context.Context) {
+	backoff := p.minBackoff
+	for {
+		b := p.head()
+		if b == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.notify:
+			}
+			continue
+		}
+
+		err := p.send(ctx, b)
+		var recoverable recoverableError
+		switch {
+		case err == nil:
+			p.requests.WithLabelValues(resultSuccess).Inc()
+			p.remove(b)
+			backoff = p.minBackoff
+		case errors.As(err, &recoverable):
+			p.requests.WithLabelValues(resultRetry).Inc()
+			p.log.Warnf("Error during remote write, retrying in %s: %s", backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxBackoff)
+		default:
+			p.requests.WithLabelValues(resultError).Inc()
+			p.log.Errorf("Error during remote write, dropping request: %s", err)
+			p.remove(b)
+		}
+	}
+}
+
+func (p *Pusher) head() *batch {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.queue) == 0 {
+		return nil
+	}
+
+	return p.queue[0]
+}
+
+// remove takes the batch from the queue, unless it has already been dropped.
+func (p *Pusher) remove(b *batch) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.queue) > 0 && p.queue[0] == b {
+		p.queue = p.queue[1:]
+	}
+}
+
+func (p *Pusher) send(ctx context.Context, b *batch) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(b.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "netatmo-exporter")
+
+	switch {
+	case p.username != "":
+		req.SetBasicAuth(p.username, p.password)
+	case p.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+p.bearerToken)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 == 2 {
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+	err = fmt.Errorf("unexpected status %s: %s", res.Status, bytes.TrimSpace(message))
+	if res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+
+	return err
+}
+
+// Describe implements prometheus.Collector
+func (p *Pusher) Describe(ch chan<- *prometheus.Desc) {
+	p.requests.Describe(ch)
+	p.dropped.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (p *Pusher) Collect(ch chan<- prometheus.Metric) {
+	p.requests.Collect(ch)
+	p.dropped.Collect(ch)
+}
@@ -0,0 +1,312 @@
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest is the counterpart of encodeWriteRequest used by the test receiver.
+func decodeWriteRequest(t *testing.T, data []byte) []timeSeries {
+	t.Helper()
+
+	var result []timeSeries
+	forEachField(t, data, func(num protowire.Number, value []byte, _ uint64) {
+		if num != 1 {
+			t.Fatalf("unexpected field %d in WriteRequest", num)
+		}
+
+		var ts timeSeries
+		forEachField(t, value, func(num protowire.Number, value []byte, _ uint64) {
+			switch num {
+			case 1:
+				var l label
+				forEachField(t, value, func(num protowire.Number, value []byte, _ uint64) {
+					if num == 1 {
+						l.name = string(value)
+					} else {
+						l.value = string(value)
+					}
+				})
+				ts.labels = append(ts.labels, l)
+			case 2:
+				var s sample
+				forEachField(t, value, func(num protowire.Number, _ []byte, number uint64) {
+					if num == 1 {
+						s.value = math.Float64frombits(number)
+					} else {
+						s.timestamp = int64(number)
+					}
+				})
+				ts.samples = append(ts.samples, s)
+			}
+		})
+		result = append(result, ts)
+	})
+
+	return result
+}
+
+func forEachField(t *testing.T, data []byte, fn func(num protowire.Number, value []byte, number uint64)) {
+	t.Helper()
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			t.Fatalf("error decoding tag: %s", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				t.Fatalf("error decoding bytes: %s", protowire.ParseError(n))
+			}
+			fn(num, value, 0)
+			data = data[n:]
+		case protowire.Fixed64Type:
+			value, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				t.Fatalf("error decoding fixed64: %s", protowire.ParseError(n))
+			}
+			fn(num, nil, value)
+			data = data[n:]
+		case protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				t.Fatalf("error decoding varint: %s", protowire.ParseError(n))
+			}
+			fn(num, nil, value)
+			data = data[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+}
+
+type receivedRequest struct {
+	header http.Header
+	series []timeSeries
+}
+
+// testReceiver is a remote write endpoint, which answers with the given status codes in order.
+// After the status codes are used up, it answers with 204.
+type testReceiver struct {
+	t        *testing.T
+	lock     sync.Mutex
+	statuses []int
+	received chan receivedRequest
+}
+
+func newTestReceiver(t *testing.T, statuses ...int) (*testReceiver, *httptest.Server) {
+	r := &testReceiver{
+		t:        t,
+		statuses: statuses,
+		received: make(chan receivedRequest, 10),
+	}
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return r, server
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	compressed, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("error reading body: %s", err)
+		return
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		r.t.Errorf("error decompressing body: %s", err)
+		return
+	}
+
+	r.received <- receivedRequest{
+		header: req.Header,
+		series: decodeWriteRequest(r.t, data),
+	}
+
+	status := http.StatusNoContent
+	r.lock.Lock()
+	if len(r.statuses) > 0 {
+		status = r.statuses[0]
+		r.statuses = r.statuses[1:]
+	}
+	r.lock.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (r *testReceiver) receive() receivedRequest {
+	r.t.Helper()
+
+	select {
+	case req := <-r.received:
+		return req
+	case <-time.After(5 * time.Second):
+		r.t.Fatal("timeout waiting for request")
+		return receivedRequest{}
+	}
+}
+
+func testRegistry() *prometheus.Registry {
+	temperature := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "netatmo_sensor_temperature_celsius",
+		Help: "Temperature measurement in celsius",
+	}, []string{"module", "site"})
+	temperature.WithLabelValues("Outside", "home").Set(-2.5)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(temperature)
+
+	return registry
+}
+
+func TestPusher(t *testing.T) {
+	receiver, server := newTestReceiver(t, http.StatusServiceUnavailable)
+	p, err := New(logrus.New(), Config{
+		URL:      server.URL,
+		Username: "user",
+		Password: "secret",
+		ExternalLabels: map[string]string{
+			"site":    "office",
+			"cluster": "weather",
+		},
+		QueueSize: DefaultQueueSize,
+	}, testRegistry(), server.Client())
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+	p.minBackoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	p.Push(time.Unix(3600, 0), nil)
+
+	wantSeries := []timeSeries{
+		{
+			labels: []label{
+				{name: "__name__", value: "netatmo_sensor_temperature_celsius"},
+				{name: "cluster", value: "weather"},
+				{name: "module", value: "Outside"},
+				{name: "site", value: "home"},
+			},
+			samples: []sample{
+				{value: -2.5, timestamp: 3600000},
+			},
+		},
+	}
+
+	// The first request fails and is retried.
+	for i := 0; i < 2; i++ {
+		req := receiver.receive()
+		if diff := cmp.Diff(wantSeries, req.series, cmp.AllowUnexported(timeSeries{}, label{}, sample{})); diff != "" {
+			t.Errorf("request %d: series differ: %s", i+1, diff)
+		}
+
+		if username, password, ok := (&http.Request{Header: req.header}).BasicAuth(); !ok || username != "user" || password != "secret" {
+			t.Errorf("request %d: got basic auth %q:%q, want user:secret", i+1, username, password)
+		}
+
+		if got := req.header.Get("Content-Encoding"); got != "snappy" {
+			t.Errorf("request %d: got content encoding %q, want snappy", i+1, got)
+		}
+	}
+
+	waitForQueue(t, p)
+
+	wantMetrics := `# HELP netatmo_exporter_remote_write_dropped_total Number of remote write requests dropped because the queue was full.
+# TYPE netatmo_exporter_remote_write_dropped_total counter
+netatmo_exporter_remote_write_dropped_total 0
+# HELP netatmo_exporter_remote_write_requests_total Number of remote write requests by result.
+# TYPE netatmo_exporter_remote_write_requests_total counter
+netatmo_exporter_remote_write_requests_total{result="error"} 0
+netatmo_exporter_remote_write_requests_total{result="retry"} 1
+netatmo_exporter_remote_write_requests_total{result="success"} 1
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(wantMetrics)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPusherBearerToken(t *testing.T) {
+	receiver, server := newTestReceiver(t, http.StatusBadRequest)
+	p, err := New(logrus.New(), Config{
+		URL:         server.URL,
+		BearerToken: "token",
+		QueueSize:   DefaultQueueSize,
+	}, testRegistry(), server.Client())
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	p.Push(time.Unix(3600, 0), nil)
+
+	req := receiver.receive()
+	if got := req.header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("got authorization %q, want %q", got, "Bearer token")
+	}
+
+	// Client errors are not retried.
+	waitForQueue(t, p)
+	if got := testutil.ToFloat64(p.requests.WithLabelValues(resultError)); got != 1 {
+		t.Errorf("got %g failed requests, want 1", got)
+	}
+}
+
+func TestPusherQueueFull(t *testing.T) {
+	p, err := New(logrus.New(), Config{
+		URL:       "http://127.0.0.1:9/",
+		QueueSize: 2,
+	}, testRegistry(), http.DefaultClient)
+	if err != nil {
+		t.Fatalf("got error %q, want none", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		p.Push(time.Unix(int64(3600+i*600), 0), nil)
+	}
+
+	if len(p.queue) != 2 {
+		t.Errorf("got queue length %d, want 2", len(p.queue))
+	}
+
+	if got := testutil.ToFloat64(p.dropped); got != 3 {
+		t.Errorf("got %g dropped requests, want 3", got)
+	}
+}
+
+func waitForQueue(t *testing.T, p *Pusher) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.head() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for empty queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
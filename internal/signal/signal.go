@@ -0,0 +1,38 @@
+// Package signal maps the signal strength reported by NetAtmo to quality levels.
+package signal
+
+var (
+	// DefaultWifiThresholds contains the Wi-Fi signal strength thresholds for the "bad" and "average" quality levels as documented by NetAtmo.
+	DefaultWifiThresholds = []int{86, 71}
+
+	// DefaultRFThresholds contains the RF signal strength thresholds for the "low", "medium" and "high" quality levels as documented by NetAtmo.
+	DefaultRFThresholds = []int{90, 80, 70}
+
+	// WifiQualities contains the quality levels of the Wi-Fi signal, from worst to best.
+	WifiQualities = []string{
+		"bad",
+		"average",
+		"good",
+	}
+
+	// RFQualities contains the quality levels of the RF signal, from worst to best.
+	RFQualities = []string{
+		"low",
+		"medium",
+		"high",
+		"full",
+	}
+)
+
+// Quality maps a signal strength value to a quality level.
+// Lower values mean a better signal, so the thresholds are expected in descending order.
+// Values below the last threshold are mapped to the last (best) quality level.
+func Quality(value int32, thresholds []int, qualities []string) string {
+	for i, threshold := range thresholds {
+		if int(value) >= threshold {
+			return qualities[i]
+		}
+	}
+
+	return qualities[len(qualities)-1]
+}
@@ -0,0 +1,82 @@
+package signal
+
+import "testing"
+
+func TestQuality(t *testing.T) {
+	tt := []struct {
+		desc        string
+		value       int32
+		thresholds  []int
+		qualities   []string
+		wantQuality string
+	}{
+		{
+			desc:        "wifi bad",
+			value:       90,
+			thresholds:  DefaultWifiThresholds,
+			qualities:   WifiQualities,
+			wantQuality: "bad",
+		},
+		{
+			desc:        "wifi average",
+			value:       71,
+			thresholds:  DefaultWifiThresholds,
+			qualities:   WifiQualities,
+			wantQuality: "average",
+		},
+		{
+			desc:        "wifi good",
+			value:       56,
+			thresholds:  DefaultWifiThresholds,
+			qualities:   WifiQualities,
+			wantQuality: "good",
+		},
+		{
+			desc:        "wifi custom thresholds",
+			value:       75,
+			thresholds:  []int{75, 65},
+			qualities:   WifiQualities,
+			wantQuality: "bad",
+		},
+		{
+			desc:        "rf low",
+			value:       95,
+			thresholds:  DefaultRFThresholds,
+			qualities:   RFQualities,
+			wantQuality: "low",
+		},
+		{
+			desc:        "rf medium",
+			value:       85,
+			thresholds:  DefaultRFThresholds,
+			qualities:   RFQualities,
+			wantQuality: "medium",
+		},
+		{
+			desc:        "rf high",
+			value:       70,
+			thresholds:  DefaultRFThresholds,
+			qualities:   RFQualities,
+			wantQuality: "high",
+		},
+		{
+			desc:        "rf full",
+			value:       60,
+			thresholds:  DefaultRFThresholds,
+			qualities:   RFQualities,
+			wantQuality: "full",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			quality := Quality(tc.value, tc.thresholds, tc.qualities)
+			if quality != tc.wantQuality {
+				t.Errorf("got quality %q, want %q", quality, tc.wantQuality)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package units contains conversions between the metric units used by NetAtmo and other unit systems.
+package units
+
+const (
+	inchesOfMercuryPerMillibar = 0.029529983071445
+	kilometersPerMile          = 1.609344
+	millimetersPerInch         = 25.4
+)
+
+// CelsiusToFahrenheit converts a temperature from degrees celsius to degrees fahrenheit.
+func CelsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// FahrenheitToCelsius converts a temperature from degrees fahrenheit to degrees celsius.
+func FahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// MillibarToInchesOfMercury converts a pressure from millibar (hectopascal) to inches of mercury.
+func MillibarToInchesOfMercury(mb float64) float64 {
+	return mb * inchesOfMercuryPerMillibar
+}
+
+// KilometersPerHourToMilesPerHour converts a speed from kilometers per hour to miles per hour.
+func KilometersPerHourToMilesPerHour(kph float64) float64 {
+	return kph / kilometersPerMile
+}
+
+// MillimetersToInches converts a length from millimeters to inches.
+func MillimetersToInches(mm float64) float64 {
+	return mm / millimetersPerInch
+}
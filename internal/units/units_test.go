@@ -0,0 +1,38 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConversions(t *testing.T) {
+	tt := []struct {
+		desc    string
+		convert func(float64) float64
+		value   float64
+		want    float64
+	}{
+		{desc: "freezing point to fahrenheit", convert: CelsiusToFahrenheit, value: 0, want: 32},
+		{desc: "boiling point to fahrenheit", convert: CelsiusToFahrenheit, value: 100, want: 212},
+		{desc: "negative celsius to fahrenheit", convert: CelsiusToFahrenheit, value: -40, want: -40},
+		{desc: "fahrenheit to celsius", convert: FahrenheitToCelsius, value: 98.6, want: 37},
+		{desc: "standard pressure to inhg", convert: MillibarToInchesOfMercury, value: 1013.25, want: 29.92},
+		{desc: "low pressure to inhg", convert: MillibarToInchesOfMercury, value: 950, want: 28.05},
+		{desc: "kph to mph", convert: KilometersPerHourToMilesPerHour, value: 100, want: 62.14},
+		{desc: "zero kph to mph", convert: KilometersPerHourToMilesPerHour, value: 0, want: 0},
+		{desc: "mm to inches", convert: MillimetersToInches, value: 25.4, want: 1},
+		{desc: "rain mm to inches", convert: MillimetersToInches, value: 10, want: 0.39},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.convert(tc.value)
+			if math.Abs(got-tc.want) > 0.005 {
+				t.Errorf("got %.4f, want %.4f", got, tc.want)
+			}
+		})
+	}
+}
@@ -9,10 +9,13 @@ import (
 	"github.com/exzz/netatmo-api-go"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
 )
 
 // DebugDataHandler creates a handler which outputs the raw JSON data.
-func DebugDataHandler(log logrus.FieldLogger, readFunc func() (*netatmo.DeviceCollection, error)) http.Handler {
+func DebugDataHandler(log logrus.FieldLogger, readFunc func() (*api.DeviceCollection, error)) http.Handler {
 	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
 		devices, err := readFunc()
 		if err != nil {
@@ -28,6 +31,19 @@ func DebugDataHandler(log logrus.FieldLogger, readFunc func() (*netatmo.DeviceCo
 	})
 }
 
+// DebugRefreshesHandler creates a handler which outputs the latest refresh attempts.
+func DebugRefreshesHandler(log logrus.FieldLogger, historyFunc func() []collector.RefreshAttempt) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+		wr.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(wr)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(historyFunc()); err != nil {
+			log.Errorf("Can not encode refresh history debug response: %s", err)
+			return
+		}
+	})
+}
+
 // DebugTokenHandler creates a handler which returns information about the currently-used token.
 // For security reasons, the actual token data is not returned.
 func DebugTokenHandler(log logrus.FieldLogger, tokenFunc func() (*oauth2.Token, error)) http.Handler {
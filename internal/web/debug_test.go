@@ -11,24 +11,27 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
 )
 
 func TestDebugDataHandler(t *testing.T) {
-	createCollection := func(devices []*netatmo.Device) *netatmo.DeviceCollection {
-		dc := &netatmo.DeviceCollection{}
+	createCollection := func(devices []*api.Device) *api.DeviceCollection {
+		dc := &api.DeviceCollection{}
 		dc.Body.Devices = devices
 		return dc
 	}
 	tt := []struct {
 		desc       string
-		readFunc   func() (*netatmo.DeviceCollection, error)
+		readFunc   func() (*api.DeviceCollection, error)
 		wantStatus int
 		wantBody   string
 	}{
 		{
 			desc: "success",
-			readFunc: func() (*netatmo.DeviceCollection, error) {
-				return createCollection([]*netatmo.Device{}), nil
+			readFunc: func() (*api.DeviceCollection, error) {
+				return createCollection([]*api.Device{}), nil
 			},
 			wantStatus: http.StatusOK,
 			wantBody: `{"Body":{"devices":[]}}
@@ -36,7 +39,7 @@ func TestDebugDataHandler(t *testing.T) {
 		},
 		{
 			desc: "error retrieving data",
-			readFunc: func() (*netatmo.DeviceCollection, error) {
+			readFunc: func() (*api.DeviceCollection, error) {
 				return nil, errors.New("test error")
 			},
 			wantStatus: http.StatusBadGateway,
@@ -70,6 +73,85 @@ func TestDebugDataHandler(t *testing.T) {
 	}
 }
 
+func TestDebugRefreshesHandler(t *testing.T) {
+	tt := []struct {
+		desc        string
+		historyFunc func() []collector.RefreshAttempt
+		wantBody    string
+	}{
+		{
+			desc: "empty",
+			historyFunc: func() []collector.RefreshAttempt {
+				return []collector.RefreshAttempt{}
+			},
+			wantBody: `[]
+`,
+		},
+		{
+			desc: "attempts",
+			historyFunc: func() []collector.RefreshAttempt {
+				return []collector.RefreshAttempt{
+					{
+						Start:      time.Unix(60, 0).UTC(),
+						Duration:   time.Second,
+						Error:      errors.New("test error"),
+						ErrorClass: "unknown",
+					},
+					{
+						Start:    time.Unix(0, 0).UTC(),
+						Duration: 2 * time.Second,
+						Devices:  1,
+						Modules:  3,
+					},
+				}
+			},
+			wantBody: `[
+  {
+    "start": "1970-01-01T00:01:00Z",
+    "duration": "1s",
+    "success": false,
+    "error": "test error",
+    "errorClass": "unknown",
+    "devices": 0,
+    "modules": 0
+  },
+  {
+    "start": "1970-01-01T00:00:00Z",
+    "duration": "2s",
+    "success": true,
+    "devices": 1,
+    "modules": 3
+  }
+]
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			log := logrus.New()
+			h := DebugRefreshesHandler(log, tc.historyFunc)
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("got code %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			body := rec.Body.String()
+			if diff := cmp.Diff(body, tc.wantBody); diff != "" {
+				t.Errorf("body differs: -got+want\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestDebugTokenHandler(t *testing.T) {
 	tt := []struct {
 		desc       string
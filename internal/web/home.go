@@ -9,6 +9,8 @@ import (
 	"github.com/exzz/netatmo-api-go"
 	"golang.org/x/oauth2"
 
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+
 	_ "embed"
 )
 
@@ -21,11 +23,37 @@ type homeContext struct {
 	Valid          bool
 	Token          *oauth2.Token
 	NetAtmoDevSite string
+	Refreshes      refreshSummary
+	InfluxHandler  bool
+}
+
+type refreshSummary struct {
+	Last   *collector.RefreshAttempt
+	Total  int
+	Failed int
+}
+
+func summarizeRefreshes(attempts []collector.RefreshAttempt) refreshSummary {
+	summary := refreshSummary{
+		Total: len(attempts),
+	}
+	if len(attempts) > 0 {
+		summary.Last = &attempts[0]
+	}
+
+	for _, a := range attempts {
+		if !a.Success() {
+			summary.Failed++
+		}
+	}
+
+	return summary
 }
 
 // HomeHandler produces a simple website showing the exporter's status in a human-readable form.
 // It provides links to other information and help for authentication as well.
-func HomeHandler(tokenFunc func() (*oauth2.Token, error)) http.Handler {
+// The link to the InfluxDB endpoint is only shown, when influxHandler is set.
+func HomeHandler(tokenFunc func() (*oauth2.Token, error), historyFunc func() []collector.RefreshAttempt, influxHandler bool) http.Handler {
 	homeTemplate, err := template.New("home.html").Funcs(map[string]any{
 		"remaining": remaining,
 	}).Parse(homeHtml)
@@ -47,6 +75,8 @@ func HomeHandler(tokenFunc func() (*oauth2.Token, error)) http.Handler {
 			Valid:          token.Valid(),
 			Token:          token,
 			NetAtmoDevSite: netatmoDevSite,
+			Refreshes:      summarizeRefreshes(historyFunc()),
+			InfluxHandler:  influxHandler,
 		}
 
 		wr.Header().Set("Content-Type", "text/html")
@@ -0,0 +1,77 @@
+package web
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+)
+
+func TestSummarizeRefreshes(t *testing.T) {
+	testError := errors.New("test error")
+
+	tt := []struct {
+		desc       string
+		attempts   []collector.RefreshAttempt
+		wantLast   *time.Time
+		wantTotal  int
+		wantFailed int
+	}{
+		{
+			desc: "empty history",
+		},
+		{
+			desc: "all successful",
+			attempts: []collector.RefreshAttempt{
+				{Start: time.Unix(1200, 0)},
+				{Start: time.Unix(600, 0)},
+				{Start: time.Unix(0, 0)},
+			},
+			wantLast:  timePtr(time.Unix(1200, 0)),
+			wantTotal: 3,
+		},
+		{
+			desc: "mixed errors",
+			attempts: []collector.RefreshAttempt{
+				{Start: time.Unix(1800, 0), Error: testError},
+				{Start: time.Unix(1200, 0)},
+				{Start: time.Unix(600, 0), Error: testError},
+				{Start: time.Unix(0, 0)},
+			},
+			wantLast:   timePtr(time.Unix(1800, 0)),
+			wantTotal:  4,
+			wantFailed: 2,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			summary := summarizeRefreshes(tc.attempts)
+
+			switch {
+			case tc.wantLast == nil && summary.Last != nil:
+				t.Errorf("got last attempt %v, want none", summary.Last)
+			case tc.wantLast != nil && summary.Last == nil:
+				t.Errorf("got no last attempt, want %s", tc.wantLast)
+			case tc.wantLast != nil && !summary.Last.Start.Equal(*tc.wantLast):
+				t.Errorf("got last attempt at %s, want %s", summary.Last.Start, tc.wantLast)
+			}
+
+			if summary.Total != tc.wantTotal {
+				t.Errorf("got total %d, want %d", summary.Total, tc.wantTotal)
+			}
+
+			if summary.Failed != tc.wantFailed {
+				t.Errorf("got failed %d, want %d", summary.Failed, tc.wantFailed)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -11,15 +11,21 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"golang.org/x/oauth2"
 
 	"github.com/exzz/netatmo-api-go"
+	"github.com/xperimental/netatmo-exporter/v2/internal/alert"
+	"github.com/xperimental/netatmo-exporter/v2/internal/api"
 	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
 	"github.com/xperimental/netatmo-exporter/v2/internal/config"
+	"github.com/xperimental/netatmo-exporter/v2/internal/influx"
 	"github.com/xperimental/netatmo-exporter/v2/internal/logger"
+	"github.com/xperimental/netatmo-exporter/v2/internal/mqtt"
+	"github.com/xperimental/netatmo-exporter/v2/internal/remotewrite"
 	"github.com/xperimental/netatmo-exporter/v2/internal/token"
 	"github.com/xperimental/netatmo-exporter/v2/internal/web"
 )
@@ -47,6 +53,17 @@ func main() {
 	log.Infof("netatmo-exporter %s (commit: %s)", Version, GitCommit)
 	client := netatmo.NewClient(cfg.Netatmo, tokenUpdated(cfg.TokenFile))
 
+	// All requests to the NetAtmo API, including token refreshes, use the HTTP client provided in the context.
+	apiBudget := api.NewBudget(cfg.APILimits)
+	apiTransport := api.NewTransport(nil, apiBudget)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: apiTransport,
+	})
+
+	// Functions called before exiting when a signal is received.
+	var onShutdown []func()
 	if cfg.TokenFile != "" {
 		token, err := loadToken(cfg.TokenFile)
 		switch {
@@ -64,34 +81,131 @@ func main() {
 			}
 
 			log.Infof("Loaded token from %s.", cfg.TokenFile)
-			client.InitWithToken(context.Background(), token)
+			client.InitWithToken(ctx, token)
 		}
 
-		registerSignalHandler(client, cfg.TokenFile)
+		onShutdown = append(onShutdown, func() {
+			if err := saveToken(client, cfg.TokenFile); err != nil {
+				log.Errorf("Error persisting token: %s", err)
+			}
+		})
 	} else {
 		log.Warn("No token-file set! Authentication will be lost on restart.")
 	}
 
-	metrics := collector.New(log, client.Read, cfg.RefreshInterval, cfg.StaleDuration)
-	prometheus.MustRegister(metrics)
+	apiClient := api.NewClient(ctx, client.CurrentToken, apiBudget)
+
+	registry := prometheus.NewRegistry()
+	exporterRegistry := prometheus.NewRegistry()
+	if cfg.GoCollector {
+		exporterRegistry.MustRegister(collectors.NewGoCollector())
+	}
+	if cfg.ProcessCollector {
+		exporterRegistry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	if cfg.BuildInfoCollector {
+		exporterRegistry.MustRegister(collectors.NewBuildInfoCollector())
+	}
+
+	metrics := collector.New(log, apiClient.Read, cfg.RefreshInterval, cfg.StaleDuration, collector.Names{
+		Prefix: cfg.MetricPrefix,
+		Labels: cfg.LabelNames,
+	})
+	metrics.WaitFunction = apiBudget.Wait
+	metrics.WifiThresholds = cfg.WifiThresholds
+	metrics.RFThresholds = cfg.RFThresholds
+	metrics.DerivedMetrics = cfg.DerivedMetrics
+	metrics.UnitSystem = cfg.UnitSystem
+	metrics.MetricNaming = cfg.MetricNaming
+	metrics.MeasurementTimestamps = cfg.MetricTimestamps
+	metrics.RefreshHistorySize = cfg.RefreshHistory
+	metrics.CachePolicy = cfg.CachePolicy
+	metrics.CacheMaxAge = cfg.CacheMaxAge
+	metrics.CacheFile = cfg.CacheFile
+	metrics.ModuleFilter, err = collector.NewModuleFilter(cfg.File.Modules.Include, cfg.File.Modules.Exclude)
+	if err != nil {
+		log.Fatalf("Error in module filters: %s", err)
+	}
+	if err := metrics.SetModuleLabels(cfg.File.Modules.Labels); err != nil {
+		log.Fatalf("Error in module labels: %s", err)
+	}
+	metrics.SetCalibration(cfg.File.Modules.Calibration)
+	metrics.PlausibilityCheck, err = collector.NewPlausibilityCheck(cfg.File.Plausibility)
+	if err != nil {
+		log.Fatalf("Error in plausibility rules: %s", err)
+	}
+	if len(cfg.File.Alerts.Rules) > 0 {
+		alerts, err := alert.New(log, cfg.File.Alerts, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			log.Fatalf("Error in alerts: %s", err)
+		}
+		metrics.OnRefresh = append(metrics.OnRefresh, alerts.Evaluate)
+		exporterRegistry.MustRegister(alerts)
+	}
+	if cfg.MQTT.Enabled() {
+		publisher, err := mqtt.New(log, cfg.MQTT)
+		if err != nil {
+			log.Fatalf("Error in MQTT settings: %s", err)
+		}
+		if err := publisher.Connect(); err != nil {
+			log.Fatalf("Error connecting to MQTT broker: %s", err)
+		}
+		metrics.OnRefresh = append(metrics.OnRefresh, publisher.Publish)
+		exporterRegistry.MustRegister(publisher)
+		onShutdown = append(onShutdown, publisher.Close)
+	}
+	if cfg.RemoteWrite.Enabled() {
+		pusher, err := remotewrite.New(log, cfg.RemoteWrite, registry, &http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			log.Fatalf("Error in remote write settings: %s", err)
+		}
+		go pusher.Run(ctx)
+		metrics.OnRefresh = append(metrics.OnRefresh, pusher.Push)
+		exporterRegistry.MustRegister(pusher)
+	}
+	if cfg.Influx.Enabled() {
+		writer, err := influx.NewWriter(log, cfg.Influx, &http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			log.Fatalf("Error in InfluxDB settings: %s", err)
+		}
+		metrics.OnRefresh = append(metrics.OnRefresh, writer.Write)
+		exporterRegistry.MustRegister(writer)
+	}
+	if err := metrics.LoadCache(); err != nil {
+		log.Errorf("Error loading cached data: %s", err)
+	}
+	registry.MustRegister(metrics)
+	if len(metrics.OnRefresh) > 0 {
+		// Alerts and push targets need fresh data even if the exporter is not scraped.
+		go metrics.RefreshLoop(ctx)
+	}
 
 	tokenMetric := token.Metric(client.CurrentToken)
-	prometheus.MustRegister(tokenMetric)
+	exporterRegistry.MustRegister(tokenMetric)
+	exporterRegistry.MustRegister(buildInfoMetric())
+	exporterRegistry.MustRegister(apiTransport)
+	exporterRegistry.MustRegister(apiBudget)
 
 	if cfg.DebugHandlers {
-		http.Handle("/debug/data", web.DebugDataHandler(log, client.Read))
+		http.Handle("/debug/data", web.DebugDataHandler(log, apiClient.Read))
 		http.Handle("/debug/token", web.DebugTokenHandler(log, client.CurrentToken))
+		http.Handle("/debug/refreshes", web.DebugRefreshesHandler(log, metrics.RefreshHistory))
+	}
+	if cfg.Influx.Handler {
+		http.Handle("/influx", influx.Handler(log, metrics.Readings))
 	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	http.Handle("/auth/authorize", web.AuthorizeHandler(cfg.ExternalURL, client))
 	http.Handle("/auth/callback", web.CallbackHandler(ctx, client))
 	http.Handle("/auth/settoken", web.SetTokenHandler(ctx, client))
-	http.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{}))
-	http.Handle("/version", versionHandler(log))
-	http.Handle("/", web.HomeHandler(client.CurrentToken))
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: cfg.MetricTimestamps,
+	}))
+	http.Handle("/metrics/exporter", promhttp.HandlerFor(exporterRegistry, promhttp.HandlerOpts{}))
+	http.Handle("/version", versionHandler(log, enabledFeatures(cfg), apiClient.Endpoint()))
+	http.Handle("/", web.HomeHandler(client.CurrentToken, metrics.RefreshHistory, cfg.Influx.Handler))
+
+	registerSignalHandler(onShutdown...)
 
 	log.Infof("Listen on %s...", cfg.Addr)
 	log.Fatal(http.ListenAndServe(cfg.Addr, nil))
@@ -112,7 +226,7 @@ func loadToken(fileName string) (*oauth2.Token, error) {
 	return &token, nil
 }
 
-func registerSignalHandler(client *netatmo.Client, fileName string) {
+func registerSignalHandler(shutdownFuncs ...func()) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, signals...)
 	go func() {
@@ -120,8 +234,8 @@ func registerSignalHandler(client *netatmo.Client, fileName string) {
 		signal.Reset(signals...)
 		log.Debugf("Got signal: %s", sig)
 
-		if err := saveToken(client, fileName); err != nil {
-			log.Errorf("Error persisting token: %s", err)
+		for _, fn := range shutdownFuncs {
+			fn()
 		}
 
 		os.Exit(0)
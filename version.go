@@ -3,8 +3,13 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+	"runtime"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+	"github.com/xperimental/netatmo-exporter/v2/internal/config"
 )
 
 var (
@@ -13,16 +18,110 @@ var (
 
 	// GitCommit contains the git commit hash set during the build.
 	GitCommit = "unknown"
+
+	// GitBranch contains the git branch set during the build.
+	GitBranch = "unknown"
+
+	// BuildDate contains the date of the build.
+	BuildDate = "unknown"
 )
 
-func versionHandler(log logrus.FieldLogger) http.Handler {
+func buildInfoMetric() prometheus.Collector {
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "netatmo_exporter_build_info",
+		Help: "Contains build information about the exporter as labels. The value is always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":   Version,
+			"revision":  GitCommit,
+			"branch":    GitBranch,
+			"goversion": runtime.Version(),
+		},
+	})
+	buildInfo.Set(1)
+
+	return buildInfo
+}
+
+// feature is an optional feature reported by the version endpoint.
+type feature struct {
+	name    string
+	enabled func(cfg config.Config) bool
+	// variant optionally returns a suffix for the name, like the selected unit system.
+	variant func(cfg config.Config) string
+}
+
+var features = []feature{
+	{name: "debug-handlers", enabled: func(cfg config.Config) bool { return cfg.DebugHandlers }},
+	{name: "derived-metrics", enabled: func(cfg config.Config) bool { return cfg.DerivedMetrics }},
+	{
+		name:    "unit-system",
+		enabled: func(cfg config.Config) bool { return cfg.UnitSystem != collector.UnitSystemMetric },
+		variant: func(cfg config.Config) string { return string(cfg.UnitSystem) },
+	},
+	{
+		name:    "metrics-naming",
+		enabled: func(cfg config.Config) bool { return cfg.MetricNaming != collector.MetricNamingV1 },
+		variant: func(cfg config.Config) string { return string(cfg.MetricNaming) },
+	},
+	{name: "metrics-timestamps", enabled: func(cfg config.Config) bool { return cfg.MetricTimestamps }},
+	{
+		name:    "cache-policy",
+		enabled: func(cfg config.Config) bool { return cfg.CachePolicy != collector.CachePolicyServeForever },
+		variant: func(cfg config.Config) string { return string(cfg.CachePolicy) },
+	},
+	{name: "custom-names", enabled: func(cfg config.Config) bool {
+		return cfg.MetricPrefix != collector.DefaultPrefix || len(cfg.LabelNames) > 0
+	}},
+	{name: "cache-persist", enabled: func(cfg config.Config) bool { return cfg.CachePersist }},
+	{name: "module-filters", enabled: func(cfg config.Config) bool {
+		return len(cfg.File.Modules.Include) > 0 || len(cfg.File.Modules.Exclude) > 0
+	}},
+	{name: "module-labels", enabled: func(cfg config.Config) bool { return len(cfg.File.Modules.Labels) > 0 }},
+	{name: "calibration", enabled: func(cfg config.Config) bool { return len(cfg.File.Modules.Calibration) > 0 }},
+	{name: "plausibility-checks", enabled: func(cfg config.Config) bool { return len(cfg.File.Plausibility) > 0 }},
+	{name: "alerts", enabled: func(cfg config.Config) bool { return len(cfg.File.Alerts.Rules) > 0 }},
+	{name: "mqtt", enabled: func(cfg config.Config) bool { return cfg.MQTT.Enabled() }},
+	{name: "remote-write", enabled: func(cfg config.Config) bool { return cfg.RemoteWrite.Enabled() }},
+	{name: "influx", enabled: func(cfg config.Config) bool { return cfg.Influx.Enabled() }},
+	{name: "influx-handler", enabled: func(cfg config.Config) bool { return cfg.Influx.Handler }},
+}
+
+// enabledFeatures returns a list of the optional features enabled in the configuration.
+func enabledFeatures(cfg config.Config) []string {
+	result := []string{}
+	for _, f := range features {
+		if !f.enabled(cfg) {
+			continue
+		}
+
+		name := f.name
+		if f.variant != nil {
+			name += "-" + f.variant(cfg)
+		}
+		result = append(result, name)
+	}
+
+	return result
+}
+
+func versionHandler(log logrus.FieldLogger, features []string, apiEndpoint string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		info := struct {
-			Version string `json:"version"`
-			Commit  string `json:"commit"`
+			Version     string   `json:"version"`
+			Commit      string   `json:"commit"`
+			Branch      string   `json:"branch"`
+			BuildDate   string   `json:"buildDate"`
+			GoVersion   string   `json:"goVersion"`
+			Features    []string `json:"features"`
+			APIEndpoint string   `json:"apiEndpoint"`
 		}{
-			Version: Version,
-			Commit:  GitCommit,
+			Version:     Version,
+			Commit:      GitCommit,
+			Branch:      GitBranch,
+			BuildDate:   BuildDate,
+			GoVersion:   runtime.Version(),
+			Features:    features,
+			APIEndpoint: apiEndpoint,
 		}
 
 		w.Header().Add("Content-Type", "application/json")
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xperimental/netatmo-exporter/v2/internal/collector"
+	"github.com/xperimental/netatmo-exporter/v2/internal/config"
+	"github.com/xperimental/netatmo-exporter/v2/internal/influx"
+	"github.com/xperimental/netatmo-exporter/v2/internal/mqtt"
+)
+
+func TestBuildInfoMetric(t *testing.T) {
+	wantMetrics := fmt.Sprintf(`# HELP netatmo_exporter_build_info Contains build information about the exporter as labels. The value is always 1.
+# TYPE netatmo_exporter_build_info gauge
+netatmo_exporter_build_info{branch="unknown",goversion=%q,revision="unknown",version="unknown"} 1
+`, runtime.Version())
+
+	if err := testutil.CollectAndCompare(buildInfoMetric(), strings.NewReader(wantMetrics)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEnabledFeatures(t *testing.T) {
+	tt := []struct {
+		desc         string
+		cfg          config.Config
+		wantFeatures []string
+	}{
+		{
+			desc: "defaults",
+			cfg: config.Config{
+				UnitSystem:   collector.UnitSystemMetric,
+				MetricNaming: collector.MetricNamingV1,
+				MetricPrefix: collector.DefaultPrefix,
+				CachePolicy:  collector.CachePolicyServeForever,
+			},
+			wantFeatures: []string{},
+		},
+		{
+			desc: "optional features",
+			cfg: config.Config{
+				DerivedMetrics: true,
+				UnitSystem:     collector.UnitSystemImperial,
+				MetricNaming:   collector.MetricNamingDual,
+				MetricPrefix:   collector.DefaultPrefix,
+				CachePolicy:    collector.CachePolicyServeForever,
+				MQTT: mqtt.Config{
+					Broker: "tcp://localhost:1883",
+				},
+				Influx: influx.Config{
+					Handler: true,
+				},
+			},
+			wantFeatures: []string{
+				"derived-metrics",
+				"unit-system-imperial",
+				"metrics-naming-dual",
+				"mqtt",
+				"influx-handler",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			features := enabledFeatures(tc.cfg)
+			if diff := cmp.Diff(tc.wantFeatures, features); diff != "" {
+				t.Errorf("features differ: %s", diff)
+			}
+		})
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	handler := versionHandler(logrus.New(), []string{"alerts", "mqtt"}, "https://api.example.com")
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if res.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", res.Code, http.StatusOK)
+	}
+
+	if contentType := res.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("got content type %q, want %q", contentType, "application/json")
+	}
+
+	var info map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatalf("error decoding response: %s", err)
+	}
+
+	wantInfo := map[string]interface{}{
+		"version":     "unknown",
+		"commit":      "unknown",
+		"branch":      "unknown",
+		"buildDate":   "unknown",
+		"goVersion":   runtime.Version(),
+		"features":    []interface{}{"alerts", "mqtt"},
+		"apiEndpoint": "https://api.example.com",
+	}
+	if diff := cmp.Diff(wantInfo, info); diff != "" {
+		t.Errorf("version info differs: %s", diff)
+	}
+}